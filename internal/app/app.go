@@ -1,17 +1,29 @@
 package app
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/haltman-io/gorunandcallme/internal/config"
+	"github.com/haltman-io/gorunandcallme/internal/control"
+	"github.com/haltman-io/gorunandcallme/internal/daemon"
 	"github.com/haltman-io/gorunandcallme/internal/execx"
 	"github.com/haltman-io/gorunandcallme/internal/job"
 	"github.com/haltman-io/gorunandcallme/internal/notify"
+	"github.com/haltman-io/gorunandcallme/internal/schedule"
+	"github.com/haltman-io/gorunandcallme/internal/server"
 	"github.com/haltman-io/gorunandcallme/internal/util"
 	"github.com/spf13/cobra"
 )
@@ -27,6 +39,10 @@ type RootOptions struct {
 	Debug   bool
 
 	Background bool
+	Tags       []string
+	JobName    string
+	Labels     []string
+	Queue      string
 
 	// Execution
 	ExecMode    string
@@ -43,9 +59,16 @@ type RootOptions struct {
 	// Notification options
 	Callbacks           []string
 	NotifyEach          string
-	NotifyOn            []string // start,finish
-	NotifyMode          string   // text-only|attach-only|auto|summary
-	NotifyTextSelect    string   // all|head|tail
+	NotifyOn            []string // start,finish,success,failure,timeout,interrupted
+	NotifyOnExit        []int
+	QuietSuccess        bool
+	FailureLines        int
+	Timeout             string
+	Heartbeat           string
+	StallAfter          string
+	KillOnStall         bool
+	NotifyMode          string // text-only|attach-only|auto|summary
+	NotifyTextSelect    string // all|head|tail
 	NotifyHeadLines     int
 	NotifyTailLines     int
 	AttachEnabled       bool
@@ -78,6 +101,11 @@ type RootOptions struct {
 	TelegramChatID    string
 	WebhookURL        string
 	WebhookHeaders    []string
+	WebhookSecret     string
+	WebhookPayload    string
+
+	PagerDutyRoutingKey string
+	OpsgenieAPIKey      string
 }
 
 func Main() int {
@@ -101,12 +129,27 @@ func Main() int {
 
 	cmd := buildRootCmd(root)
 	if err := cmd.Execute(); err != nil {
-		// Cobra already prints usage on some errors; keep exit code stable.
+		var ec *ExitCodeError
+		if errors.As(err, &ec) {
+			return ec.Code
+		}
+		// Errors are silenced in cobra; report them here once.
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		return 1
 	}
 	return 0
 }
 
+// ExitCodeError makes Main exit with Code without printing an error,
+// e.g. to propagate a job's exit code from "job wait".
+type ExitCodeError struct {
+	Code int
+}
+
+func (e *ExitCodeError) Error() string {
+	return fmt.Sprintf("exit code %d", e.Code)
+}
+
 func buildRootCmd(o *RootOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "gorunandcallme [flags] -- <command> [args...]",
@@ -129,11 +172,29 @@ Background jobs:
 		SilenceUsage:  true,
 		SilenceErrors: true,
 		Args:          func(cmd *cobra.Command, args []string) error { return nil },
-		RunE: func(cmd *cobra.Command, args []string) error {
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
 			if missingCommand(o, args, cmd) {
 				return cmd.Help()
 			}
 
+			// Spawned by --background: record our own lifecycle in the job meta.
+			worker, err := job.AttachWorker()
+			if err != nil {
+				return err
+			}
+			if worker != nil {
+				defer worker.Recover()
+				defer func() {
+					if err != nil {
+						worker.Finish(1, err)
+					}
+				}()
+				// A worker runs its job; it never spawns another one.
+				if o.Background || o.Queue != "" {
+					return errors.New("worker: --background/--queue cannot be used inside a background job")
+				}
+			}
+
 			ui := NewUI(o.NoColor, o.Verbose, o.Debug)
 			ui.BannerIfAllowed(o.Silent)
 
@@ -145,13 +206,25 @@ Background jobs:
 
 			stateDir := resolveStateDir(o, cfg)
 
+			// Retention runs once per invocation, in the launching process only.
+			if worker == nil {
+				applyRetention(ui, cfg, stateDir)
+			} else {
+				maxLog, segments, err := logRotation(cfg.Retention)
+				if err != nil {
+					return err
+				}
+				defer worker.RotateLogs(maxLog, segments, 5*time.Second)()
+			}
+
 			// Validate conflicts: debug/verbose cannot be enabled with notifications.
 			if (o.Debug || o.Verbose) && notify.HasCallbacks(o.Callbacks, cfg) {
 				return errors.New("debug/verbose output cannot be used with notifications (callbacks) enabled. Disable --debug/--verbose or remove --callback/notify config")
 			}
 
 			// Background: spawn detached child and return job-id.
-			if o.Background {
+			// --queue implies --background.
+			if o.Background || o.Queue != "" {
 				st, err := job.NewStore(stateDir)
 				if err != nil {
 					return err
@@ -164,28 +237,72 @@ Background jobs:
 
 				argsForChild := stripBackgroundFlag(os.Args[1:])
 
-				res, err := job.SpawnBackground(cmd.Context(), job.SpawnOptions{
-					RunnerPath: exe,
-					RunnerArgs: argsForChild,
-					Workdir:    o.WorkingDir,
-					Store:      st,
-				})
+				command := o.CommandStr
+				if command == "" {
+					command = job.QuoteArgs(args)
+				}
+				if err := job.ValidateJobName(o.JobName); err != nil {
+					return err
+				}
+				labels, err := job.ParseLabels(o.Labels)
+				if err != nil {
+					return err
+				}
+
+				// Files the job writes besides its log, bundled by "job export".
+				var outputFiles []string
+				eventOutput := cfg.EventOutput
+				if o.EventOutput != "" {
+					eventOutput = o.EventOutput
+				}
+				for _, f := range []string{o.OutputFile, eventOutput} {
+					if f == "" {
+						continue
+					}
+					if abs, err := filepath.Abs(f); err == nil {
+						f = abs
+					}
+					outputFiles = append(outputFiles, f)
+				}
+
+				spawn := job.SpawnOptions{
+					RunnerPath:  exe,
+					RunnerArgs:  argsForChild,
+					Store:       st,
+					Command:     command,
+					Tags:        util.NormalizeCSV(o.Tags),
+					Name:        o.JobName,
+					Labels:      labels,
+					OutputFiles: outputFiles,
+					Queue:       o.Queue,
+				}
+				res, viaDaemon, err := spawnJob(cmd.Context(), spawn)
 				if err != nil {
 					return err
 				}
+				if res.Meta.Status == job.StatusQueued {
+					if !viaDaemon {
+						if err := ensureSupervisor(st, exe, o); err != nil {
+							return err
+						}
+					}
+					ui.Info("Queued background job: %s (queue=%s)", res.JobID, o.Queue)
+					ui.Info("Cancel: gorunandcallme job cancel %s", res.JobID)
+					return nil
+				}
 				ui.Info("Started background job: %s", res.JobID)
 				ui.Info("Follow: gorunandcallme job follow %s", res.JobID)
 				return nil
 			}
 
 			// Foreground run
-			return runForeground(ui, cfg, stateDir, o, args)
+			return runForeground(ui, cfg, stateDir, o, args, worker)
 		},
 	}
 
 	// Global flags
-	cmd.Flags().StringVar(&o.Profile, "profile", "default", "Config profile name (from YAML).")
-	cmd.Flags().StringVar(&o.Config, "config", "", "Path to YAML config file.")
+	cmd.PersistentFlags().StringVar(&o.Profile, "profile", "default", "Config profile name (from YAML).")
+	cmd.PersistentFlags().StringVar(&o.Config, "config", "", "Path to YAML config file.")
 	cmd.PersistentFlags().StringVar(&o.StateDir, "state-dir", "", "State directory for jobs, logs, offsets (default: ~/.gorunandcallme).")
 
 	cmd.Flags().BoolVarP(&o.Silent, "silent", "s", false, "Disable banner and non-essential UI output.")
@@ -194,6 +311,10 @@ Background jobs:
 	cmd.Flags().BoolVar(&o.Debug, "debug", false, "Debug diagnostics to stderr (cannot be used with notifications).")
 
 	cmd.Flags().BoolVar(&o.Background, "background", false, "Run as a background job and detach from terminal.")
+	cmd.Flags().StringSliceVar(&o.Tags, "tag", nil, "Tag a background job (repeatable or comma-separated); shown and filterable in 'job list'.")
+	cmd.Flags().StringVar(&o.JobName, "job-name", "", "Name a background job; usable instead of the job ID in 'job' subcommands.")
+	cmd.Flags().StringArrayVar(&o.Labels, "label", nil, "Label a background job KEY=VALUE (repeatable); included in lifecycle notifications.")
+	cmd.Flags().StringVar(&o.Queue, "queue", "", "Run as a background job in this queue; waits while the queue's max_concurrency (config 'queues') is reached.")
 
 	// Execution flags
 	cmd.Flags().StringVar(&o.ExecMode, "exec-mode", o.ExecMode, "Execution mode: direct|shell|bash|zsh|pwsh|cmd|custom")
@@ -209,9 +330,16 @@ Background jobs:
 	cmd.Flags().BoolVar(&o.NoTTYOutput, "no-tty-output", false, "Do not mirror child output to your terminal (useful for pure notification jobs).")
 
 	// Notifications + platform flags
-	cmd.Flags().StringSliceVar(&o.Callbacks, "callback", nil, "Callbacks to enable (comma-separated): discord,slack,telegram,webhook,all")
+	cmd.Flags().StringSliceVar(&o.Callbacks, "callback", nil, "Callbacks to enable (comma-separated): discord,slack,telegram,webhook,pagerduty,opsgenie,mqtt,nats,redis,exec,file,syslog,all")
 	cmd.Flags().StringVar(&o.NotifyEach, "notify-each", "", "Notify interval (supports: s,m,h,d,w,mo,y). Example: 10s, 5m, 1h, 1d, 1w.")
-	cmd.Flags().StringSliceVar(&o.NotifyOn, "notify-on", nil, "Lifecycle notifications: start,finish (repeatable or comma-separated).")
+	cmd.Flags().StringSliceVar(&o.NotifyOn, "notify-on", nil, "Lifecycle notifications: start,finish,success,failure,timeout,interrupted (repeatable or comma-separated). finish covers every outcome.")
+	cmd.Flags().IntSliceVar(&o.NotifyOnExit, "notify-on-exit", nil, "Always notify the finish when the command exits with one of these codes. Example: 2,3.")
+	cmd.Flags().BoolVar(&o.QuietSuccess, "quiet-success", false, "Send nothing but alerts unless the run fails, times out or is interrupted.")
+	cmd.Flags().IntVar(&o.FailureLines, "failure-lines", 0, "Last N stderr lines included in failure notifications (default 20, -1 = none).")
+	cmd.Flags().StringVar(&o.Timeout, "timeout", "", "Stop the command after this long and exit with code 124. Example: 2h.")
+	cmd.Flags().StringVar(&o.Heartbeat, "heartbeat", "", "Send a \"still running\" message with elapsed time, lines seen and the last line at this interval. Example: 1h.")
+	cmd.Flags().StringVar(&o.StallAfter, "stall-after", "", "Alert when no output arrives for this long. Example: 20m.")
+	cmd.Flags().BoolVar(&o.KillOnStall, "kill-on-stall", false, "Stop the command when it stalls (requires --stall-after).")
 	cmd.Flags().StringVar(&o.NotifyMode, "notify-mode", o.NotifyMode, "Notify mode: text-only|attach-only|auto|summary")
 	cmd.Flags().StringVar(&o.NotifyTextSelect, "notify-text-select", o.NotifyTextSelect, "Text selection: all|head|tail")
 	cmd.Flags().IntVar(&o.NotifyHeadLines, "notify-head-lines", o.NotifyHeadLines, "If head selection: send first N lines.")
@@ -250,9 +378,18 @@ Background jobs:
 	cmd.Flags().StringVar(&o.TelegramChatID, "telegram-chat-id", "", "Telegram chat ID (overrides config).")
 	cmd.Flags().StringVar(&o.WebhookURL, "webhook-url", "", "Generic webhook URL (overrides config).")
 	cmd.Flags().StringArrayVar(&o.WebhookHeaders, "webhook-header", nil, "Generic webhook header KEY=VALUE (repeatable).")
+	cmd.Flags().StringVar(&o.WebhookSecret, "webhook-secret", "", "Sign generic webhook requests with HMAC-SHA256 using this secret.")
+	cmd.Flags().StringVar(&o.WebhookPayload, "webhook-payload", "", "Generic webhook body: text|structured|template (overrides config).")
+	cmd.Flags().StringVar(&o.PagerDutyRoutingKey, "pagerduty-routing-key", "", "PagerDuty Events v2 routing key (overrides config).")
+	cmd.Flags().StringVar(&o.OpsgenieAPIKey, "opsgenie-api-key", "", "Opsgenie API key (overrides config).")
 
 	// Job subcommands
 	cmd.AddCommand(buildJobCmd(o))
+	cmd.AddCommand(buildScheduleCmd(o))
+	cmd.AddCommand(buildSuperviseCmd(o))
+	cmd.AddCommand(buildDaemonCmd(o))
+	cmd.AddCommand(buildServeCmd(o))
+	cmd.AddCommand(buildControlCmd(o))
 
 	installHelpWithBanner(cmd, o)
 
@@ -268,20 +405,69 @@ func buildJobCmd(o *RootOptions) *cobra.Command {
 	listCmd := &cobra.Command{
 		Use:   "list",
 		Short: "List background jobs",
+		Example: strings.TrimRight(`
+  gorunandcallme job list --status running,failed --since 24h
+  gorunandcallme job list --grep nmap --json
+  gorunandcallme job list --format '{{.ID}} {{.Status}} {{.Command}}'`, "\n"),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			st, err := newJobStore(o)
 			if err != nil {
 				return err
 			}
 			limit, _ := cmd.Flags().GetInt("limit")
-			return job.CmdList(cmd.OutOrStdout(), st, job.ListOptions{Limit: limit})
+			statusList, _ := cmd.Flags().GetStringSlice("status")
+			sinceStr, _ := cmd.Flags().GetString("since")
+			grep, _ := cmd.Flags().GetString("grep")
+			labels, _ := cmd.Flags().GetStringArray("label")
+			asJSON, _ := cmd.Flags().GetBool("json")
+			format, _ := cmd.Flags().GetString("format")
+			noColor, _ := cmd.Flags().GetBool("no-color")
+
+			statuses, err := job.ParseStatuses(statusList)
+			if err != nil {
+				return err
+			}
+			var since time.Duration
+			if sinceStr != "" {
+				since, err = util.ParseExtendedDuration(sinceStr)
+				if err != nil {
+					return fmt.Errorf("--since: %w", err)
+				}
+			}
+
+			opt := job.ListOptions{
+				Limit:    limit,
+				Statuses: statuses,
+				Since:    since,
+				Grep:     grep,
+				Labels:   labels,
+				JSON:     asJSON,
+				Format:   format,
+				Color:    !noColor && util.IsTerminal(os.Stdout),
+			}
+			if c := dialDaemon(st); c != nil {
+				defer c.Close()
+				jobs, err := c.List(opt)
+				if err != nil {
+					return err
+				}
+				return job.RenderList(cmd.OutOrStdout(), jobs, opt)
+			}
+			return job.CmdList(cmd.OutOrStdout(), st, opt)
 		},
 	}
 	listCmd.Flags().Int("limit", 0, "Show at most N jobs (0 = all)")
+	listCmd.Flags().StringSlice("status", nil, "Only jobs with these statuses (comma-separated): queued,starting,running,finished,failed,stopped,lost,canceled")
+	listCmd.Flags().String("since", "", "Only jobs started within this window (supports: s,m,h,d,w,mo,y). Example: 24h, 7d")
+	listCmd.Flags().String("grep", "", "Only jobs whose command contains this substring (case-insensitive)")
+	listCmd.Flags().StringArray("label", nil, "Only jobs with this label: KEY=VALUE or KEY (repeatable, all must match)")
+	listCmd.Flags().Bool("json", false, "Print jobs as a JSON array")
+	listCmd.Flags().String("format", "", "Print each job with a Go template, e.g. '{{.ID}} {{.Status}} {{.ExitCode}}'")
+	listCmd.Flags().Bool("no-color", false, "Disable colors in the table (off when stdout is not a terminal)")
 	jobCmd.AddCommand(listCmd)
 
 	statusCmd := &cobra.Command{
-		Use:   "status <job-id>",
+		Use:   "status <job-id|name|index>",
 		Short: "Show job status",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -289,13 +475,18 @@ func buildJobCmd(o *RootOptions) *cobra.Command {
 			if err != nil {
 				return err
 			}
+			if ok, err := viaDaemon(cmd.OutOrStdout(), st, func(c *daemon.Client) (string, error) {
+				return c.Status(args[0])
+			}); ok {
+				return err
+			}
 			return job.CmdStatus(cmd.OutOrStdout(), st, args[0])
 		},
 	}
 	jobCmd.AddCommand(statusCmd)
 
 	followCmd := &cobra.Command{
-		Use:   "follow <job-id>",
+		Use:   "follow <job-id|name|index>",
 		Short: "Follow job log output",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -323,7 +514,7 @@ func buildJobCmd(o *RootOptions) *cobra.Command {
 	jobCmd.AddCommand(followCmd)
 
 	stopCmd := &cobra.Command{
-		Use:   "stop <job-id>",
+		Use:   "stop <job-id|name|index>",
 		Short: "Stop a background job",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -331,13 +522,195 @@ func buildJobCmd(o *RootOptions) *cobra.Command {
 			if err != nil {
 				return err
 			}
-			return job.CmdStop(cmd.OutOrStdout(), st, args[0])
+			sig, _ := cmd.Flags().GetString("signal")
+			grace, _ := cmd.Flags().GetDuration("grace")
+			opt := job.StopOptions{Signal: sig, Grace: grace}
+			if ok, err := viaDaemon(cmd.OutOrStdout(), st, func(c *daemon.Client) (string, error) {
+				return c.Stop(args[0], opt)
+			}); ok {
+				return err
+			}
+			return job.CmdStop(cmd.OutOrStdout(), st, args[0], opt)
 		},
 	}
+	stopCmd.Flags().String("signal", "TERM", "Signal sent to the job's process group (TERM, INT, HUP, QUIT, USR1, USR2, KILL or a number)")
+	stopCmd.Flags().Duration("grace", 10*time.Second, "Wait this long for the job to exit before sending SIGKILL")
 	jobCmd.AddCommand(stopCmd)
 
+	waitCmd := &cobra.Command{
+		Use:   "wait <job-id|name|index>...",
+		Short: "Wait for jobs to finish and exit with their exit code",
+		Example: strings.TrimRight(`
+  gorunandcallme job wait scan1 --timeout 2h
+  gorunandcallme job wait 0 1 2 --any`, "\n"),
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			st, err := newJobStore(o)
+			if err != nil {
+				return err
+			}
+			anyJob, _ := cmd.Flags().GetBool("any")
+			all, _ := cmd.Flags().GetBool("all")
+			anyJob = anyJob || !all
+			timeout, _ := cmd.Flags().GetDuration("timeout")
+			poll, _ := cmd.Flags().GetDuration("poll")
+
+			code, err := job.CmdWait(cmd.Context(), cmd.OutOrStdout(), st, args, job.WaitOptions{
+				Any:     anyJob,
+				Timeout: timeout,
+				Poll:    poll,
+			})
+			if err != nil {
+				return err
+			}
+			if code != 0 {
+				return &ExitCodeError{Code: code}
+			}
+			return nil
+		},
+	}
+	waitCmd.Flags().Bool("any", false, "Return as soon as one job ends, with its exit code")
+	waitCmd.Flags().Bool("all", true, "Wait for all jobs; exit with the first non-zero exit code (default)")
+	waitCmd.Flags().Duration("timeout", 0, "Give up after this long and exit with code 124 (0 = wait forever)")
+	waitCmd.Flags().Duration("poll", 500*time.Millisecond, "Polling interval for job status")
+	waitCmd.MarkFlagsMutuallyExclusive("any", "all")
+	jobCmd.AddCommand(waitCmd)
+
+	pruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete old jobs and logs by age, status, count or total size",
+		Long: strings.TrimSpace(`
+Delete ended jobs. Running jobs are never pruned.
+
+--older-than, --status and --keep-last select jobs together; --max-total-size
+then removes the oldest remaining jobs until the jobs directory fits.
+Without flags, the "retention:" block of the config file is used.
+		`),
+		Example: strings.TrimRight(`
+  gorunandcallme job prune --older-than 30d --status finished
+  gorunandcallme job prune --keep-last 50 --max-total-size 10G --dry-run`, "\n"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadMergedConfig(o)
+			if err != nil {
+				return err
+			}
+			st, err := job.NewStore(resolveStateDir(o, cfg))
+			if err != nil {
+				return err
+			}
+
+			olderThan, _ := cmd.Flags().GetString("older-than")
+			statuses, _ := cmd.Flags().GetStringSlice("status")
+			keepLast, _ := cmd.Flags().GetInt("keep-last")
+			maxTotal, _ := cmd.Flags().GetString("max-total-size")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+			ret := cfg.Retention
+			if cmd.Flags().Changed("older-than") || cmd.Flags().Changed("status") ||
+				cmd.Flags().Changed("keep-last") || cmd.Flags().Changed("max-total-size") {
+				ret = config.RetentionConfig{
+					OlderThan:    olderThan,
+					Statuses:     statuses,
+					KeepLast:     keepLast,
+					MaxTotalSize: maxTotal,
+				}
+			}
+			opt, err := pruneOptions(ret)
+			if err != nil {
+				return err
+			}
+			if opt.Empty() {
+				return errors.New("prune: no criteria (use flags or a retention: config block)")
+			}
+			opt.DryRun = dryRun
+			return job.CmdPrune(cmd.OutOrStdout(), st, opt)
+		},
+	}
+	pruneCmd.Flags().String("older-than", "", "Prune jobs started longer ago than this (supports: s,m,h,d,w,mo,y)")
+	pruneCmd.Flags().StringSlice("status", nil, "Only prune jobs with these statuses: finished,failed,stopped,lost,canceled")
+	pruneCmd.Flags().Int("keep-last", 0, "Always keep the newest N jobs")
+	pruneCmd.Flags().String("max-total-size", "", "Prune oldest jobs while the jobs directory exceeds this size (e.g. 10G, 500M)")
+	pruneCmd.Flags().Bool("dry-run", false, "Show what would be pruned without deleting")
+	jobCmd.AddCommand(pruneCmd)
+
+	exportCmd := &cobra.Command{
+		Use:   "export <job-id|name|index>",
+		Short: "Bundle a job (meta, logs, output files) into a tar.gz with checksums",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			st, err := newJobStore(o)
+			if err != nil {
+				return err
+			}
+			out, _ := cmd.Flags().GetString("output")
+			return job.CmdExport(cmd.OutOrStdout(), st, args[0], out)
+		},
+	}
+	exportCmd.Flags().StringP("output", "o", "", "Bundle path (default: <job-id>.tar.gz)")
+	jobCmd.AddCommand(exportCmd)
+
+	importCmd := &cobra.Command{
+		Use:   "import <bundle.tar.gz>",
+		Short: "Restore a job bundle into the state dir, keeping its ID",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			st, err := newJobStore(o)
+			if err != nil {
+				return err
+			}
+			return job.CmdImport(cmd.OutOrStdout(), st, args[0])
+		},
+	}
+	jobCmd.AddCommand(importCmd)
+
+	rerunCmd := &cobra.Command{
+		Use:   "rerun <job-id|name|index> [-- extra runner flags]",
+		Short: "Start a finished job again as a new background job",
+		Example: strings.TrimRight(`
+  gorunandcallme job rerun scan1
+  gorunandcallme job rerun 0 -- --notify-each 1m --callback slack`, "\n"),
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRerun(cmd, o, args, nil)
+		},
+	}
+	jobCmd.AddCommand(rerunCmd)
+
+	restartCmd := &cobra.Command{
+		Use:   "restart <job-id|name|index> [-- extra runner flags]",
+		Short: "Stop a running job and start it again",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sig, _ := cmd.Flags().GetString("signal")
+			grace, _ := cmd.Flags().GetDuration("grace")
+			return runRerun(cmd, o, args, &job.StopOptions{Signal: sig, Grace: grace})
+		},
+	}
+	restartCmd.Flags().String("signal", "TERM", "Signal used to stop the running job")
+	restartCmd.Flags().Duration("grace", 10*time.Second, "Wait this long for the job to exit before sending SIGKILL")
+	jobCmd.AddCommand(restartCmd)
+
+	cancelCmd := &cobra.Command{
+		Use:   "cancel <job-id|name|index>",
+		Short: "Remove a queued job from its queue",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			st, err := newJobStore(o)
+			if err != nil {
+				return err
+			}
+			if ok, err := viaDaemon(cmd.OutOrStdout(), st, func(c *daemon.Client) (string, error) {
+				return c.Cancel(args[0])
+			}); ok {
+				return err
+			}
+			return job.CmdCancel(cmd.OutOrStdout(), st, args[0])
+		},
+	}
+	jobCmd.AddCommand(cancelCmd)
+
 	purgeCmd := &cobra.Command{
-		Use:   "purge <job-id>",
+		Use:   "purge <job-id|name|index>",
 		Short: "Delete a job and its logs",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -345,6 +718,11 @@ func buildJobCmd(o *RootOptions) *cobra.Command {
 			if err != nil {
 				return err
 			}
+			if ok, err := viaDaemon(cmd.OutOrStdout(), st, func(c *daemon.Client) (string, error) {
+				return c.Purge(args[0])
+			}); ok {
+				return err
+			}
 			return job.CmdPurge(cmd.OutOrStdout(), st, args[0])
 		},
 	}
@@ -353,7 +731,736 @@ func buildJobCmd(o *RootOptions) *cobra.Command {
 	return jobCmd
 }
 
-func runForeground(ui *UI, cfg *config.Config, stateDir string, o *RootOptions, args []string) error {
+// runRerun implements "job rerun" and "job restart" (stop != nil).
+// Everything after "--" is passed as extra runner flags.
+func runRerun(cmd *cobra.Command, o *RootOptions, args []string, stop *job.StopOptions) error {
+	ref := args[0]
+	var extra []string
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		if dash > 1 {
+			return fmt.Errorf("unexpected arguments before --: %v", args[1:dash])
+		}
+		extra = args[dash:]
+	} else if len(args) > 1 {
+		return fmt.Errorf("unexpected arguments: %v (put extra runner flags after --)", args[1:])
+	}
+
+	st, err := newJobStore(o)
+	if err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if c := dialDaemon(st); c != nil {
+		defer c.Close()
+		reply, err := c.Rerun(daemon.RerunArgs{Ref: ref, ExtraArgs: extra, Stop: stop, Env: os.Environ()})
+		fmt.Fprint(cmd.OutOrStdout(), reply.Text)
+		return err
+	}
+
+	res, err := job.CmdRerun(cmd.Context(), cmd.OutOrStdout(), st, ref, job.RerunOptions{
+		RunnerPath: exe,
+		ExtraArgs:  extra,
+		Stop:       stop,
+	})
+	if err != nil {
+		return err
+	}
+	if res.Meta.Status == job.StatusQueued {
+		return ensureSupervisor(st, exe, o)
+	}
+	return nil
+}
+
+// buildSuperviseCmd is the hidden supervisor started by --queue and
+// "schedule add". It starts queued jobs as slots free up, launches due
+// schedules and exits once there is nothing left to do.
+func buildSuperviseCmd(o *RootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:    "supervise",
+		Short:  "Start queued background jobs (internal)",
+		Hidden: true,
+		Args:   cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadMergedConfig(o)
+			if err != nil {
+				return err
+			}
+			st, err := job.NewStore(resolveStateDir(o, cfg))
+			if err != nil {
+				return err
+			}
+			sst, err := schedule.NewStore(st.BaseDir)
+			if err != nil {
+				return err
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return job.Supervise(ctx, st, job.SuperviseOptions{
+				Limit: cfg.QueueLimit,
+				Tick: func(now time.Time) bool {
+					return schedule.Tick(st, sst, now, cmd.ErrOrStderr())
+				},
+				Log: cmd.ErrOrStderr(),
+			})
+		},
+	}
+}
+
+func buildScheduleCmd(o *RootOptions) *cobra.Command {
+	schedCmd := &cobra.Command{
+		Use:   "schedule",
+		Short: "Manage scheduled (recurring) background jobs",
+	}
+
+	var (
+		cronExpr, every, name, missed, queue string
+		overlap                              bool
+		tags, labels, runArgs                []string
+	)
+	addCmd := &cobra.Command{
+		Use:   "add (--cron <expr> | --every <interval>) [flags] -- <command> [args...]",
+		Short: "Add a schedule that starts the command as a background job",
+		Example: strings.TrimRight(`
+  gorunandcallme schedule add --cron "0 3 * * *" --name nightly -- nmap -sV example.com
+  gorunandcallme schedule add --every 1d --missed once --run-arg=--notify-on=finish -- subfinder -d example.com`, "\n"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if (cronExpr == "") == (every == "") {
+				return errors.New("schedule add: set exactly one of --cron and --every")
+			}
+			if cmd.ArgsLenAtDash() != 0 || len(args) == 0 {
+				return errors.New("schedule add: put the command after --")
+			}
+			if err := job.ValidateJobName(name); err != nil {
+				return err
+			}
+			policy, err := schedule.ParseMissedPolicy(missed)
+			if err != nil {
+				return err
+			}
+			lbls, err := job.ParseLabels(labels)
+			if err != nil {
+				return err
+			}
+
+			st, sst, err := newScheduleStore(o)
+			if err != nil {
+				return err
+			}
+			exe, err := os.Executable()
+			if err != nil {
+				return err
+			}
+			wd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+
+			runnerArgs, err := runnerBaseArgs(st, o)
+			if err != nil {
+				return err
+			}
+			runnerArgs = append(runnerArgs, runArgs...)
+			runnerArgs = append(append(runnerArgs, "--"), args...)
+
+			s, err := schedule.Add(sst, schedule.Schedule{
+				Name:       name,
+				Cron:       cronExpr,
+				Every:      every,
+				Missed:     policy,
+				Overlap:    overlap,
+				Command:    job.QuoteArgs(args),
+				RunnerPath: exe,
+				RunnerArgs: runnerArgs,
+				Workdir:    wd,
+				Queue:      queue,
+				Tags:       util.NormalizeCSV(tags),
+				Labels:     lbls,
+			})
+			if err != nil {
+				return err
+			}
+			if err := ensureSupervisor(st, exe, o); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "added: %s (%s, next run %s)\n",
+				s.ID, s.SpecString(), s.NextRun.Format(time.RFC3339))
+			return nil
+		},
+	}
+	addCmd.Flags().StringVar(&cronExpr, "cron", "", "Cron expression: minute hour day month weekday (local time), or @daily, @hourly, ...")
+	addCmd.Flags().StringVar(&every, "every", "", "Run every interval (supports: m,h,d,w,mo,y), counted from now. Example: 6h, 1d.")
+	addCmd.Flags().StringVar(&name, "name", "", "Schedule name; also the job name of its runs.")
+	addCmd.Flags().StringVar(&missed, "missed", "skip", "Runs missed while no supervisor was running: skip|once|catchup")
+	addCmd.Flags().BoolVar(&overlap, "overlap", false, "Start a run even if the previous one is still active (default: skip it).")
+	addCmd.Flags().StringVar(&queue, "queue", "", "Start runs in this job queue.")
+	addCmd.Flags().StringSliceVar(&tags, "tag", nil, "Tag the jobs (repeatable or comma-separated).")
+	addCmd.Flags().StringArrayVar(&labels, "label", nil, "Label the jobs KEY=VALUE (repeatable).")
+	addCmd.Flags().StringArrayVar(&runArgs, "run-arg", nil, "Extra runner flag for every run, e.g. --run-arg=--notify-each=10m (repeatable).")
+	schedCmd.AddCommand(addCmd)
+
+	var listJSON bool
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List schedules",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			st, sst, err := newScheduleStore(o)
+			if err != nil {
+				return err
+			}
+			if err := schedule.CmdList(cmd.OutOrStdout(), sst, listJSON); err != nil {
+				return err
+			}
+			// Schedules only fire while a supervisor runs (e.g. not after a reboot).
+			if list, err := sst.List(); err == nil && !job.SupervisorRunning(st) {
+				for _, s := range list {
+					if !s.Paused {
+						fmt.Fprintln(cmd.ErrOrStderr(), "note: no supervisor is running; start one with: gorunandcallme schedule resume <schedule-id|name>")
+						break
+					}
+				}
+			}
+			return nil
+		},
+	}
+	listCmd.Flags().BoolVar(&listJSON, "json", false, "Print schedules as JSON.")
+	schedCmd.AddCommand(listCmd)
+
+	removeCmd := &cobra.Command{
+		Use:     "remove <schedule-id|name>",
+		Aliases: []string{"rm"},
+		Short:   "Delete a schedule (jobs it started are kept)",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, sst, err := newScheduleStore(o)
+			if err != nil {
+				return err
+			}
+			id, err := schedule.Remove(sst, args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "removed: %s\n", id)
+			return nil
+		},
+	}
+	schedCmd.AddCommand(removeCmd)
+
+	for _, paused := range []bool{true, false} {
+		paused := paused
+		use, short, verb := "pause", "Stop starting runs of a schedule", "paused"
+		if !paused {
+			use, short, verb = "resume", "Resume a paused schedule from now on", "resumed"
+		}
+		schedCmd.AddCommand(&cobra.Command{
+			Use:   use + " <schedule-id|name>",
+			Short: short,
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				st, sst, err := newScheduleStore(o)
+				if err != nil {
+					return err
+				}
+				s, err := schedule.SetPaused(sst, args[0], paused)
+				if err != nil {
+					return err
+				}
+				if !paused {
+					exe, err := os.Executable()
+					if err != nil {
+						return err
+					}
+					if err := ensureSupervisor(st, exe, o); err != nil {
+						return err
+					}
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", verb, s.ID)
+				return nil
+			},
+		})
+	}
+
+	return schedCmd
+}
+
+// newScheduleStore opens the job store and the schedule store inside it.
+func newScheduleStore(o *RootOptions) (*job.Store, *schedule.Store, error) {
+	st, err := newJobStore(o)
+	if err != nil {
+		return nil, nil, err
+	}
+	sst, err := schedule.NewStore(st.BaseDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	return st, sst, nil
+}
+
+// runnerBaseArgs are the global flags a detached runner needs to load the
+// same config, profile and state dir as this invocation.
+func runnerBaseArgs(st *job.Store, o *RootOptions) ([]string, error) {
+	args := []string{"--state-dir", st.BaseDir, "--profile", o.Profile}
+	if o.Config != "" {
+		path, err := filepath.Abs(o.Config)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "--config", path)
+	}
+	return args, nil
+}
+
+// buildDaemonCmd runs the optional job daemon in the foreground (e.g. under
+// systemd). While it runs, job commands go through its control socket.
+func buildDaemonCmd(o *RootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "daemon",
+		Short: "Run the job daemon: spawns and reaps jobs, runs queues and schedules, serves a control socket",
+		Long: strings.TrimSpace(`
+The daemon owns job spawning, reaping, queues and schedules, and answers job
+commands over JSON-RPC on <state-dir>/daemon.sock. Without it, job commands
+work directly on the job files and a short-lived supervisor handles queues
+and schedules.`),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadMergedConfig(o)
+			if err != nil {
+				return err
+			}
+			st, err := job.NewStore(resolveStateDir(o, cfg))
+			if err != nil {
+				return err
+			}
+			exe, err := os.Executable()
+			if err != nil {
+				return err
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return daemon.Serve(ctx, st, daemon.Options{
+				RunnerPath: exe,
+				Limit:      cfg.QueueLimit,
+				Log:        cmd.ErrOrStderr(),
+			})
+		},
+	}
+}
+
+// envAPIToken supplies the serve token when neither --token nor the config sets one.
+const envAPIToken = "GORUNANDCALLME_API_TOKEN"
+
+func buildServeCmd(o *RootOptions) *cobra.Command {
+	var listen, token, readToken string
+	dashboard := true
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve a local HTTP API and web dashboard for jobs",
+		Long: strings.TrimSpace(`
+Serves a REST API over the job store and streams live job output as
+Server-Sent Events, using the --event-output event schema.
+
+  GET    /api/jobs                 list (?status=&since=&grep=&label=&limit=)
+  POST   /api/jobs                 start {"command": [...], "args": [...], "name", "tags", "labels", "queue", "workdir"}
+  GET    /api/jobs/{ref}           status
+  POST   /api/jobs/{ref}/stop      stop {"signal", "grace"}
+  DELETE /api/jobs/{ref}           purge an ended job
+  GET    /api/jobs/{ref}/events    SSE stream of output lines (?tail=N)
+  GET    /api/jobs/{ref}/log       log lines (?q=regex&alerts=1&tail=N, ?download=1)
+  GET    /api/jobs/{ref}/files/{n} download the n-th output file
+  GET    /healthz                  liveness, no auth
+  GET    /                         read-only dashboard (asks for the token)
+
+Every /api request needs "Authorization: Bearer <token>". The token comes from
+--token, server.token or GORUNANDCALLME_API_TOKEN. The read-only token
+(--read-token or server.read_token) is accepted on GET routes only; use it
+for the dashboard.`),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadMergedConfig(o)
+			if err != nil {
+				return err
+			}
+			if listen == "" {
+				listen = cfg.Server.Listen
+			}
+			if listen == "" {
+				listen = "127.0.0.1:8787"
+			}
+			if token == "" {
+				token = cfg.Server.Token
+			}
+			if token == "" {
+				token = os.Getenv(envAPIToken)
+			}
+			if strings.TrimSpace(token) == "" {
+				return fmt.Errorf("serve: an API token is required (--token, server.token or %s)", envAPIToken)
+			}
+			if readToken == "" {
+				readToken = cfg.Server.ReadToken
+			}
+			st, err := job.NewStore(resolveStateDir(o, cfg))
+			if err != nil {
+				return err
+			}
+			exe, err := os.Executable()
+			if err != nil {
+				return err
+			}
+			baseArgs, err := runnerBaseArgs(st, o)
+			if err != nil {
+				return err
+			}
+			alerts, err := notify.NewAlerts(cfg.Notify.Alerts)
+			if err != nil {
+				return fmt.Errorf("alert patterns: %w", err)
+			}
+			redact, err := notify.NewRedactor(cfg.Notify.Redaction)
+			if err != nil {
+				return fmt.Errorf("redaction: %w", err)
+			}
+			srv, err := server.New(server.Options{
+				Store:          st,
+				Token:          token,
+				ReadToken:      strings.TrimSpace(readToken),
+				RunnerPath:     exe,
+				RunnerBaseArgs: baseArgs,
+				EventOutput:    cfg.EventOutput,
+				Alerts:         alerts,
+				Redact:         redact,
+				Dashboard:      dashboard,
+				Spawn: func(ctx context.Context, opt job.SpawnOptions) (job.SpawnResult, error) {
+					res, viaDaemon, err := spawnJob(ctx, opt)
+					if err == nil && !viaDaemon && res.Meta.Status == job.StatusQueued {
+						err = ensureSupervisor(st, exe, o)
+					}
+					return res, err
+				},
+			})
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return listenAndServe(ctx, cmd.ErrOrStderr(), "serve", listen, srv)
+		},
+	}
+	cmd.Flags().StringVar(&listen, "listen", "", "listen address (default: server.listen or 127.0.0.1:8787)")
+	cmd.Flags().StringVar(&token, "token", "", "API bearer token (default: server.token or $"+envAPIToken+")")
+	cmd.Flags().StringVar(&readToken, "read-token", "", "read-only bearer token for GET routes, e.g. the dashboard (default: server.read_token)")
+	cmd.Flags().BoolVar(&dashboard, "dashboard", true, "serve the read-only web dashboard at /")
+	return cmd
+}
+
+func buildControlCmd(o *RootOptions) *cobra.Command {
+	controlCmd := &cobra.Command{
+		Use:   "control",
+		Short: "Control jobs remotely from chat apps",
+	}
+
+	var apiURL string
+	var allowChats []string
+	telegramCmd := &cobra.Command{
+		Use:   "telegram",
+		Short: "Answer job commands sent to the Telegram bot (/jobs, /status, /tail, /stop, /rerun)",
+		Long: strings.TrimSpace(`
+Long-polls the Bot API for commands and runs them on the job store:
+
+  /jobs [status...]   newest jobs
+  /status <job>       job status
+  /tail <job> [n]     last n log lines (default 20)
+  /stop <job>         stop a running job
+  /rerun <job>        start the job again
+
+Only chats in telegram.allowed_chats (or --allow-chat; default: chat_id) are
+answered. telegram.api_url (or --api-url) points at another Bot API server,
+e.g. a local fake one for testing.`),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadMergedConfig(o)
+			if err != nil {
+				return err
+			}
+			st, err := job.NewStore(resolveStateDir(o, cfg))
+			if err != nil {
+				return err
+			}
+			exe, err := os.Executable()
+			if err != nil {
+				return err
+			}
+			if apiURL == "" {
+				apiURL = cfg.Telegram.APIURL
+			}
+			if len(allowChats) == 0 {
+				allowChats = cfg.Telegram.AllowedChats
+			}
+			if len(allowChats) == 0 && cfg.Telegram.ChatID != "" {
+				allowChats = []string{cfg.Telegram.ChatID}
+			}
+			jobs, err := controlJobs(st, exe, o, cfg, cmd.ErrOrStderr())
+			if err != nil {
+				return err
+			}
+			bot, err := control.NewTelegramBot(control.TelegramOptions{
+				APIURL:       apiURL,
+				BotToken:     cfg.Telegram.BotToken,
+				AllowedChats: allowChats,
+				Jobs:         jobs,
+				Log:          cmd.ErrOrStderr(),
+			})
+			if err != nil {
+				return err
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return bot.Run(ctx)
+		},
+	}
+	telegramCmd.Flags().StringVar(&apiURL, "api-url", "", "Bot API base URL (default: telegram.api_url or https://api.telegram.org)")
+	telegramCmd.Flags().StringArrayVar(&allowChats, "allow-chat", nil, "chat ID allowed to send commands (repeatable; default: telegram.allowed_chats or chat_id)")
+	controlCmd.AddCommand(telegramCmd)
+
+	var listen, discordAPI string
+	receiverCmd := &cobra.Command{
+		Use:   "receiver",
+		Short: "Receive Slack and Discord button clicks (Stop job, Send full log, Mute alerts 1h)",
+		Long: strings.TrimSpace(`
+Serves the interaction endpoints for the buttons that slack.interactive and
+discord.interactive add to lifecycle and alert messages of background jobs:
+
+  POST /slack/interactions     Slack app "Interactivity" request URL,
+                               verified with slack.signing_secret
+  POST /discord/interactions   Discord application "Interactions Endpoint
+                               URL", verified with discord.public_key
+
+Expose it through a TLS reverse proxy or tunnel; Slack and Discord must be
+able to reach it. With slack.bot_token set, "Send full log" uploads the log
+as a file; otherwise the last lines are posted. Discord buttons need
+discord.webhook_url to be a webhook owned by that application; channel
+webhooks get messages without buttons.`),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadMergedConfig(o)
+			if err != nil {
+				return err
+			}
+			st, err := job.NewStore(resolveStateDir(o, cfg))
+			if err != nil {
+				return err
+			}
+			exe, err := os.Executable()
+			if err != nil {
+				return err
+			}
+			httpc, err := notify.NewHTTPClient(cfg.Transport)
+			if err != nil {
+				return err
+			}
+			jobs, err := controlJobs(st, exe, o, cfg, cmd.ErrOrStderr())
+			if err != nil {
+				return err
+			}
+			h, err := control.NewInteractions(control.InteractionOptions{
+				Jobs:               jobs,
+				SlackSigningSecret: cfg.Slack.SigningSecret,
+				SlackBotToken:      cfg.Slack.BotToken,
+				DiscordPublicKey:   cfg.Discord.PublicKey,
+				DiscordAPIURL:      discordAPI,
+				HTTP:               httpc,
+				Log:                cmd.ErrOrStderr(),
+			})
+			if err != nil {
+				return err
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return listenAndServe(ctx, cmd.ErrOrStderr(), "receiver", listen, h)
+		},
+	}
+	receiverCmd.Flags().StringVar(&listen, "listen", "127.0.0.1:8790", "listen address")
+	receiverCmd.Flags().StringVar(&discordAPI, "discord-api-url", "", "Discord API base URL for follow-ups (default: https://discord.com/api/v10)")
+	_ = receiverCmd.Flags().MarkHidden("discord-api-url")
+	controlCmd.AddCommand(receiverCmd)
+
+	return controlCmd
+}
+
+// controlJobs runs remote job commands on st. Reruns that end up queued get a
+// supervisor unless a daemon runs; log lines get the notification redaction.
+func controlJobs(st *job.Store, exe string, o *RootOptions, cfg *config.Config, log io.Writer) (*control.Jobs, error) {
+	// Same redaction rules as the config's runs (redaction.defaults and
+	// patterns); the --redact-* run flags do not exist on these subcommands.
+	red, err := notify.NewRedactor(cfg.Notify.Redaction)
+	if err != nil {
+		return nil, err
+	}
+	return &control.Jobs{
+		Store:      st,
+		RunnerPath: exe,
+		Redact:     red,
+		Spawned: func(res job.SpawnResult) {
+			if res.Meta.Status != job.StatusQueued {
+				return
+			}
+			if c := dialDaemon(st); c != nil {
+				_ = c.Close()
+				return
+			}
+			if err := ensureSupervisor(st, exe, o); err != nil {
+				fmt.Fprintf(log, "warning: start supervisor: %v\n", err)
+			}
+		},
+	}, nil
+}
+
+// listenAndServe serves h on addr until ctx is done, then shuts down gracefully.
+func listenAndServe(ctx context.Context, log io.Writer, name, addr string, h http.Handler) error {
+	hs := &http.Server{
+		Addr:              addr,
+		Handler:           h,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- hs.ListenAndServe() }()
+	fmt.Fprintf(log, "%s: listening on http://%s\n", name, addr)
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return hs.Shutdown(shutdownCtx)
+}
+
+// dialDaemon returns a client for the daemon serving st, or nil when none is
+// running; job commands then work on the job files directly.
+func dialDaemon(st *job.Store) *daemon.Client {
+	c, err := daemon.Dial(st.BaseDir)
+	if err != nil {
+		return nil
+	}
+	return c
+}
+
+// viaDaemon runs call against the daemon, if one is running, and prints its
+// output. It reports false when there is no daemon.
+func viaDaemon(w io.Writer, st *job.Store, call func(c *daemon.Client) (string, error)) (bool, error) {
+	c := dialDaemon(st)
+	if c == nil {
+		return false, nil
+	}
+	defer c.Close()
+	out, err := call(c)
+	fmt.Fprint(w, out)
+	return true, err
+}
+
+// spawnJob starts a background job through the daemon when one is running,
+// otherwise directly. It reports whether the daemon was used.
+func spawnJob(ctx context.Context, opt job.SpawnOptions) (job.SpawnResult, bool, error) {
+	c := dialDaemon(opt.Store)
+	if c == nil {
+		res, err := job.SpawnBackground(ctx, opt)
+		return res, false, err
+	}
+	defer c.Close()
+	// The daemon runs elsewhere; resolve the working directory here.
+	if opt.Workdir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return job.SpawnResult{}, true, err
+		}
+		opt.Workdir = wd
+	}
+	res, err := c.Start(daemon.StartArgs{
+		RunnerArgs:  opt.RunnerArgs,
+		Workdir:     opt.Workdir,
+		Command:     opt.Command,
+		Tags:        opt.Tags,
+		Name:        opt.Name,
+		Labels:      opt.Labels,
+		OutputFiles: opt.OutputFiles,
+		Queue:       opt.Queue,
+		Env:         os.Environ(),
+	})
+	return res, true, err
+}
+
+// ensureSupervisor starts the queue supervisor with the same config, profile
+// and state dir as this invocation, unless one is already running.
+func ensureSupervisor(st *job.Store, exe string, o *RootOptions) error {
+	args, err := runnerBaseArgs(st, o)
+	if err != nil {
+		return err
+	}
+	return job.EnsureSupervisor(st, exe, append([]string{"supervise"}, args...))
+}
+
+// pruneOptions converts a retention config block into prune options.
+func pruneOptions(r config.RetentionConfig) (job.PruneOptions, error) {
+	opt := job.PruneOptions{KeepLast: r.KeepLast}
+	var err error
+	if r.OlderThan != "" {
+		if opt.OlderThan, err = util.ParseExtendedDuration(r.OlderThan); err != nil {
+			return opt, fmt.Errorf("retention older_than: %w", err)
+		}
+	}
+	if opt.Statuses, err = job.ParseStatuses(r.Statuses); err != nil {
+		return opt, fmt.Errorf("retention statuses: %w", err)
+	}
+	if r.MaxTotalSize != "" {
+		if opt.MaxTotalSize, err = util.ParseByteSize(r.MaxTotalSize); err != nil {
+			return opt, fmt.Errorf("retention max_total_size: %w", err)
+		}
+	}
+	return opt, nil
+}
+
+// logRotation returns the job log rotation limits from the retention config.
+func logRotation(r config.RetentionConfig) (int64, int, error) {
+	if r.MaxLogSize == "" {
+		return 0, 0, nil
+	}
+	n, err := util.ParseByteSize(r.MaxLogSize)
+	if err != nil {
+		return 0, 0, fmt.Errorf("retention max_log_size: %w", err)
+	}
+	return n, r.MaxLogSegments, nil
+}
+
+// applyRetention prunes the job store per the retention config. Failures are
+// reported but never block the run.
+func applyRetention(ui *UI, cfg *config.Config, stateDir string) {
+	opt, err := pruneOptions(cfg.Retention)
+	if err != nil {
+		ui.Warn("%v", err)
+		return
+	}
+	if opt.Empty() {
+		return
+	}
+	st, err := job.NewStore(stateDir)
+	if err != nil {
+		ui.Warn("retention: %v", err)
+		return
+	}
+	res, err := job.Prune(st, opt)
+	if err != nil {
+		ui.Warn("retention: %v", err)
+	}
+	if len(res) > 0 {
+		ui.Verbosef("retention: pruned %d job(s)", len(res))
+	}
+}
+
+func runForeground(ui *UI, cfg *config.Config, stateDir string, o *RootOptions, args []string, worker *job.Worker) error {
 	// Merge CLI notify overrides over config notify.
 	runtimeCfg := cfg.Clone()
 
@@ -369,6 +1476,35 @@ func runForeground(ui *UI, cfg *config.Config, stateDir string, o *RootOptions,
 	if len(o.NotifyOn) > 0 {
 		runtimeCfg.Notify.NotifyOn = util.NormalizeCSV(o.NotifyOn)
 	}
+	if err := notify.ValidateNotifyOn(runtimeCfg.Notify.NotifyOn); err != nil {
+		return err
+	}
+	if len(o.NotifyOnExit) > 0 {
+		runtimeCfg.Notify.NotifyOnExit = o.NotifyOnExit
+	}
+	if o.QuietSuccess {
+		runtimeCfg.Notify.QuietSuccess = true
+	}
+	if o.FailureLines != 0 {
+		runtimeCfg.Notify.FailureLines = o.FailureLines
+	}
+	var timeout time.Duration
+	if o.Timeout != "" {
+		d, err := util.ParseExtendedDuration(o.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid --timeout: %w", err)
+		}
+		timeout = d
+	}
+	if o.Heartbeat != "" {
+		runtimeCfg.Notify.Heartbeat = o.Heartbeat
+	}
+	if o.StallAfter != "" {
+		runtimeCfg.Notify.StallAfter = o.StallAfter
+	}
+	if o.KillOnStall {
+		runtimeCfg.Notify.KillOnStall = true
+	}
 	if o.NotifyMode != "" {
 		runtimeCfg.Notify.Mode = o.NotifyMode
 	}
@@ -402,6 +1538,10 @@ func runForeground(ui *UI, cfg *config.Config, stateDir string, o *RootOptions,
 	}
 	runtimeCfg.Notify.Alerts.IncludeContextLines = o.AlertContextLines
 
+	if o.EventOutput != "" {
+		runtimeCfg.EventOutput = o.EventOutput
+	}
+
 	runtimeCfg.Notify.StripANSI = o.StripANSI
 	runtimeCfg.Notify.StripProgress = o.StripProg
 
@@ -426,11 +1566,40 @@ func runForeground(ui *UI, cfg *config.Config, stateDir string, o *RootOptions,
 		return err
 	}
 
+	// Foreground runs have no job ID; use a per-run ID for incident dedup keys.
+	runID := worker.JobID()
+	if runID == "" {
+		runID = util.NewID("run")
+	}
+	var jobMeta job.Meta
+	if worker != nil {
+		jobMeta, _ = worker.Meta()
+	}
+
 	// Build dispatcher (optional)
 	var disp *notify.Dispatcher
 	var agg *notify.Aggregator
 	var evt *notify.EventSink
 
+	// --timeout, --kill-on-stall and interrupts of foreground runs end the
+	// command through runCtx.
+	var runCtx context.Context
+	var cancelRun context.CancelFunc
+	if timeout > 0 {
+		runCtx, cancelRun = context.WithTimeout(context.Background(), timeout)
+	} else {
+		runCtx, cancelRun = context.WithCancel(context.Background())
+	}
+	defer cancelRun()
+	var stalledFor atomic.Int64
+	var interrupted atomic.Bool
+
+	// Also used to mark alert lines in --event-output.
+	alerts, err := notify.NewAlerts(runtimeCfg.Notify.Alerts)
+	if err != nil {
+		return err
+	}
+
 	if notify.HasCallbacks(nil, runtimeCfg) {
 		httpc, err := notify.NewHTTPClient(runtimeCfg.Transport)
 		if err != nil {
@@ -455,11 +1624,6 @@ func runForeground(ui *UI, cfg *config.Config, stateDir string, o *RootOptions,
 		if err != nil {
 			return err
 		}
-		alerts, err := notify.NewAlerts(runtimeCfg.Notify.Alerts)
-		if err != nil {
-			return err
-		}
-
 		agg, err = notify.NewAggregator(notify.AggregatorOptions{
 			Config:   runtimeCfg.Notify,
 			Redactor: red,
@@ -467,6 +1631,18 @@ func runForeground(ui *UI, cfg *config.Config, stateDir string, o *RootOptions,
 			Alerts:   alerts,
 			UI:       ui,
 			Dispatch: disp,
+			JobID:    runID,
+			Command:  plan.Describe(),
+			JobName:  jobMeta.Name,
+			Labels:   jobMeta.Labels,
+
+			AlertsMuted: worker.AlertsMuted,
+			JobControls: worker != nil,
+			OnStall: func(idle time.Duration) {
+				stalledFor.Store(int64(idle))
+				ui.Warn("no output for %s, stopping the command", idle.Round(time.Second))
+				cancelRun()
+			},
 		})
 		if err != nil {
 			return err
@@ -475,18 +1651,21 @@ func runForeground(ui *UI, cfg *config.Config, stateDir string, o *RootOptions,
 		evt = notify.NewEventSink(runtimeCfg.EventOutput, ui)
 	} else {
 		evt = notify.NewEventSink(runtimeCfg.EventOutput, ui)
+		if runtimeCfg.Notify.Heartbeat != "" || runtimeCfg.Notify.StallAfter != "" {
+			ui.Warn("--heartbeat and --stall-after need a notification callback; ignoring")
+		}
 	}
 
 	// Start/finish notifications (semantic)
 	fullCmd := strings.Join(os.Args, " ")
-	if agg != nil {
-		if notify.WantsLifecycle(runtimeCfg.Notify.NotifyOn, "start") {
-			agg.SendLifecycle("started", fullCmd, plan.Describe())
-		}
+	if agg.WantsStart() {
+		agg.SendLifecycle("started", fullCmd, plan.Describe(), nil)
 	}
 
 	// Run plan (scheduler)
+	var children sync.Map // child PIDs, killed on a second interrupt
 	runner := execx.NewScheduler(execx.SchedulerOptions{
+		Context:    runCtx,
 		Threads:    o.Parallel,
 		UI:         ui,
 		EventSink:  evt,
@@ -496,18 +1675,58 @@ func runForeground(ui *UI, cfg *config.Config, stateDir string, o *RootOptions,
 		NotifyHook: agg,
 		OutputFile: o.OutputFile,
 		OutputMode: o.OutputMode,
+		OnStart: func(pid int) {
+			children.Store(pid, struct{}{})
+			worker.ChildStarted(pid)
+		},
+		LineFields: alerts.LineFields,
 	})
 
+	stopSignals := worker.HandleSignals()
+	if worker == nil {
+		stopSignals = handleInterrupt(func() {
+			interrupted.Store(true)
+			cancelRun()
+		}, func() {
+			children.Range(func(pid, _ any) bool {
+				_ = execx.KillProcessGroup(pid.(int))
+				return true
+			})
+		})
+	}
 	exitCode, runErr := runner.Run(plan)
+	stopSignals()
+
+	outcome := notify.OutcomeSuccess
+	switch {
+	case errors.Is(runCtx.Err(), context.DeadlineExceeded):
+		outcome = notify.OutcomeTimeout
+		exitCode = 124
+		runErr = fmt.Errorf("timed out after %s", timeout)
+	case interrupted.Load() || worker.Signaled() != nil:
+		outcome = notify.OutcomeInterrupted
+	case stalledFor.Load() > 0:
+		outcome = notify.OutcomeFailure
+		runErr = fmt.Errorf("killed: no output for %s", time.Duration(stalledFor.Load()).Round(time.Second))
+	case runErr != nil || exitCode != 0:
+		outcome = notify.OutcomeFailure
+	}
+	finishDetails := fmt.Sprintf("%s | exit=%d", plan.Describe(), exitCode)
 	if runErr != nil {
+		finishDetails += " | " + runErr.Error()
 		ui.Error("%v", runErr)
 	}
 
 	if agg != nil {
+		agg.Close()
+		if agg.Quiet(outcome, exitCode) {
+			agg.Discard()
+		}
 		agg.FlushAll("final")
-		if notify.WantsLifecycle(runtimeCfg.Notify.NotifyOn, "finish") {
-			agg.SendLifecycle("finished", fullCmd, fmt.Sprintf("%s | exit=%d", plan.Describe(), exitCode))
+		if agg.WantsFinish(outcome, exitCode) {
+			agg.SendFinish(fullCmd, finishDetails, outcome, exitCode)
 		}
+		agg.ResolveIncidents()
 		disp.Close()
 	}
 
@@ -515,12 +1734,49 @@ func runForeground(ui *UI, cfg *config.Config, stateDir string, o *RootOptions,
 		evt.Close()
 	}
 
+	// Recorded after notifications are flushed, so a finished job has nothing pending.
+	worker.Finish(exitCode, runErr)
+
 	if exitCode != 0 {
-		return fmt.Errorf("child exited with code %d", exitCode)
+		// Exit with the child's code (124 on --timeout), without an error line.
+		return &ExitCodeError{Code: exitCode}
 	}
 	return nil
 }
 
+// handleInterrupt calls fn on the first SIGINT, SIGTERM or SIGHUP of a
+// foreground run, instead of exiting without notifications. A second signal
+// calls kill and exits right away.
+func handleInterrupt(fn func(), kill func()) func() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ch:
+			fn()
+		case <-stop:
+			return
+		}
+		select {
+		case sig := <-ch:
+			// The children run in their own process groups, out of reach
+			// of the terminal: kill them before leaving.
+			kill()
+			code := 130
+			if s, ok := sig.(syscall.Signal); ok {
+				code = 128 + int(s)
+			}
+			os.Exit(code)
+		case <-stop:
+		}
+	}()
+	return func() {
+		signal.Stop(ch)
+		close(stop)
+	}
+}
+
 func loadMergedConfig(o *RootOptions) (*config.Config, error) {
 	return config.LoadMerged(config.LoadOptions{
 		ConfigPath: o.Config,
@@ -535,6 +1791,11 @@ func loadMergedConfig(o *RootOptions) (*config.Config, error) {
 			TelegramChatID:    o.TelegramChatID,
 			WebhookURL:        o.WebhookURL,
 			WebhookHeaders:    o.WebhookHeaders,
+			WebhookSecret:     o.WebhookSecret,
+			WebhookPayload:    o.WebhookPayload,
+
+			PagerDutyRoutingKey: o.PagerDutyRoutingKey,
+			OpsgenieAPIKey:      o.OpsgenieAPIKey,
 		},
 	})
 }
@@ -586,6 +1847,15 @@ func stripBackgroundFlag(args []string) []string {
 			continue
 		}
 
+		// The worker must run the job, not queue it again.
+		if a == "--queue" {
+			skipNext = true
+			continue
+		}
+		if strings.HasPrefix(a, "--queue=") {
+			continue
+		}
+
 		out = append(out, a)
 	}
 
@@ -16,7 +16,18 @@ type Config struct {
 	Slack       SlackConfig      `yaml:"slack"`
 	Telegram    TelegramConfig   `yaml:"telegram"`
 	Webhook     WebhookConfig    `yaml:"webhook"`
+	PagerDuty   PagerDutyConfig  `yaml:"pagerduty"`
+	Opsgenie    OpsgenieConfig   `yaml:"opsgenie"`
+	MQTT        MQTTConfig       `yaml:"mqtt"`
+	NATS        NATSConfig       `yaml:"nats"`
+	Redis       RedisConfig      `yaml:"redis"`
+	Exec        ExecConfig       `yaml:"exec"`
+	File        FileSinkConfig   `yaml:"file"`
+	Syslog      SyslogConfig     `yaml:"syslog"`
 	EventOutput string          `yaml:"event_output"`
+	Retention   RetentionConfig  `yaml:"retention"`
+	Queues      map[string]QueueConfig `yaml:"queues"`
+	Server      ServerConfig    `yaml:"server"`
 	Profiles    map[string]*ProfileConfig `yaml:"profiles"`
 }
 
@@ -26,6 +37,18 @@ type ProfileConfig struct {
 	Slack    *SlackConfig    `yaml:"slack"`
 	Telegram *TelegramConfig `yaml:"telegram"`
 	Webhook  *WebhookConfig  `yaml:"webhook"`
+
+	PagerDuty *PagerDutyConfig `yaml:"pagerduty"`
+	Opsgenie  *OpsgenieConfig  `yaml:"opsgenie"`
+
+	MQTT  *MQTTConfig  `yaml:"mqtt"`
+	NATS  *NATSConfig  `yaml:"nats"`
+	Redis *RedisConfig `yaml:"redis"`
+
+	Exec *ExecConfig     `yaml:"exec"`
+	File *FileSinkConfig `yaml:"file"`
+
+	Syslog *SyslogConfig `yaml:"syslog"`
 }
 
 type TransportConfig struct {
@@ -38,11 +61,19 @@ type TransportConfig struct {
 type NotifyConfig struct {
 	Callbacks     []string `yaml:"callbacks"`
 	NotifyEach    string   `yaml:"notify_each"`
-	NotifyOn      []string `yaml:"notify_on"` // start, finish
+	NotifyOn      []string `yaml:"notify_on"` // start, finish, success, failure, timeout, interrupted
 	Mode          string   `yaml:"mode"`      // text-only | attach-only | auto | summary
 	StripANSI     string   `yaml:"strip_ansi"`
 	StripProgress string   `yaml:"strip_progress"`
 
+	Heartbeat   string `yaml:"heartbeat"`     // "still running" message interval, e.g. 1h
+	StallAfter  string `yaml:"stall_after"`   // alert when no output arrives for this long, e.g. 20m
+	KillOnStall bool   `yaml:"kill_on_stall"` // stop the job when it stalls
+
+	NotifyOnExit []int `yaml:"notify_on_exit"` // exit codes that are always notified
+	QuietSuccess bool  `yaml:"quiet_success"`  // send nothing but alerts unless the run fails
+	FailureLines int   `yaml:"failure_lines"`  // stderr lines included when a run fails (default 20, -1 = none)
+
 	Text     NotifyTextConfig   `yaml:"text"`
 	Attach   NotifyAttachConfig `yaml:"attach"`
 	Filters  NotifyFilterConfig `yaml:"filters"`
@@ -50,6 +81,19 @@ type NotifyConfig struct {
 	Alerts   AlertsConfig       `yaml:"alerts"`
 }
 
+// UnmarshalYAML keeps redaction.defaults on unless the document turns it
+// off, so a notify section (e.g. in a profile) does not disable it by omission.
+func (n *NotifyConfig) UnmarshalYAML(v *yaml.Node) error {
+	type plain NotifyConfig
+	p := plain(*n)
+	p.Redaction.Defaults = true
+	if err := v.Decode(&p); err != nil {
+		return err
+	}
+	*n = NotifyConfig(p)
+	return nil
+}
+
 type NotifyTextConfig struct {
 	Select    string `yaml:"select"` // all | head | tail
 	HeadLines int    `yaml:"head_lines"`
@@ -75,29 +119,179 @@ type RedactionConfig struct {
 }
 
 type AlertsConfig struct {
-	Patterns            []string `yaml:"patterns"`
-	IncludeContextLines int      `yaml:"include_context_lines"`
+	Patterns            []string    `yaml:"patterns"`
+	IncludeContextLines int         `yaml:"include_context_lines"`
+	Rules               []AlertRule `yaml:"rules"`
+
+	// Throttling. Matches of a pattern within Cooldown of its last alert,
+	// or beyond MaxPerHour alerts, are coalesced into "N more matches"
+	// digests; identical matched lines within DedupWindow are dropped.
+	Cooldown    string `yaml:"cooldown"`     // per pattern, e.g. 1m
+	DedupWindow string `yaml:"dedup_window"` // e.g. 10m
+	MaxPerHour  int    `yaml:"max_per_hour"` // 0 = unlimited
+}
+
+// AlertRule is a named alert. Each entry of AlertsConfig.Patterns is a rule
+// with only a pattern. A line triggers the first matching rule.
+type AlertRule struct {
+	Name         string   `yaml:"name"`
+	Pattern      string   `yaml:"pattern"`
+	Severity     string   `yaml:"severity"`      // info | warn | critical (default warn)
+	ContextLines int      `yaml:"context_lines"` // default include_context_lines
+	MinCount     int      `yaml:"min_count"`     // matches within Window before firing (default 1)
+	Window       string   `yaml:"window"`        // for min_count and absent; empty = the whole run
+	Absent       bool     `yaml:"absent"`        // fire when the pattern does NOT match within Window
+	Destinations []string `yaml:"destinations"`  // callbacks to notify (default all)
 }
 
 type DiscordConfig struct {
 	WebhookURL string `yaml:"webhook_url"`
+
+	// Interactive adds job control buttons to lifecycle and alert messages;
+	// clicks are verified with the application's PublicKey (hex) by
+	// "control receiver".
+	Interactive bool   `yaml:"interactive"`
+	PublicKey   string `yaml:"public_key"`
 }
 
 type SlackConfig struct {
 	WebhookURL string `yaml:"webhook_url"`
 	BotToken   string `yaml:"bot_token"`
 	Channel    string `yaml:"channel"`
+
+	// Interactive adds job control buttons to lifecycle and alert messages;
+	// clicks are verified with the app's SigningSecret by "control receiver".
+	Interactive   bool   `yaml:"interactive"`
+	SigningSecret string `yaml:"signing_secret"`
 }
 
 type TelegramConfig struct {
 	BotToken   string `yaml:"bot_token"`
 	ChatID     string `yaml:"chat_id"`
 	ParseMode  string `yaml:"parse_mode"` // MarkdownV2
+
+	// APIURL is the Bot API base URL (default: https://api.telegram.org),
+	// e.g. a local Bot API server or a fake one in tests.
+	APIURL string `yaml:"api_url"`
+
+	// AllowedChats may send commands to "control telegram" (default: chat_id).
+	AllowedChats []string `yaml:"allowed_chats"`
 }
 
 type WebhookConfig struct {
 	URL     string            `yaml:"url"`
 	Headers map[string]string `yaml:"headers"`
+
+	Payload      string `yaml:"payload"`       // text | structured | template (default: text)
+	BodyTemplate string `yaml:"body_template"` // Go text/template rendering JSON (payload=template)
+
+	// HMAC-SHA256 signing: signature = hex(HMAC(secret, timestamp + "." + body)),
+	// sent as "sha256=<hex>" together with the unix timestamp header.
+	Secret          string `yaml:"secret"`
+	SignatureHeader string `yaml:"signature_header"` // default: X-Gorunandcallme-Signature
+	TimestampHeader string `yaml:"timestamp_header"` // default: X-Gorunandcallme-Timestamp
+}
+
+// PagerDutyConfig configures the PagerDuty Events API v2 client.
+// It only receives alerts (trigger) and, optionally, a resolve on finish.
+type PagerDutyConfig struct {
+	RoutingKey      string `yaml:"routing_key"`
+	EventsURL       string `yaml:"events_url"` // default: https://events.pagerduty.com/v2/enqueue
+	Source          string `yaml:"source"`     // default: hostname
+	Severity        string `yaml:"severity"`   // critical | error | warning | info
+	ResolveOnFinish bool   `yaml:"resolve_on_finish"`
+}
+
+// OpsgenieConfig configures the Opsgenie Alert API client.
+type OpsgenieConfig struct {
+	APIKey          string   `yaml:"api_key"`
+	APIURL          string   `yaml:"api_url"`  // default: https://api.opsgenie.com (EU: https://api.eu.opsgenie.com)
+	Priority        string   `yaml:"priority"` // P1..P5
+	Tags            []string `yaml:"tags"`
+	ResolveOnFinish bool     `yaml:"resolve_on_finish"`
+}
+
+// Message-bus sinks publish every notification as an event JSON document.
+// Topic/subject/channel templates accept {job_id}, {kind} and {state}.
+
+type MQTTConfig struct {
+	URL      string `yaml:"url"`   // mqtt://host:1883 | mqtts://host:8883
+	Topic    string `yaml:"topic"` // default: gorunandcallme/{job_id}/{state}
+	ClientID string `yaml:"client_id"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	QoS      int    `yaml:"qos"` // 0 | 1
+	Retain   bool   `yaml:"retain"`
+}
+
+type NATSConfig struct {
+	URL      string `yaml:"url"`     // nats://host:4222 | tls://host:4222
+	Subject  string `yaml:"subject"` // default: gorunandcallme.{job_id}.{state}
+	Token    string `yaml:"token"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+type RedisConfig struct {
+	URL      string `yaml:"url"`     // redis://[:password@]host:6379/0 | rediss://...
+	Channel  string `yaml:"channel"` // default: gorunandcallme:{job_id}:{state}
+	Password string `yaml:"password"`
+}
+
+// ExecConfig runs a local command for every notification.
+// The message is written to stdin; metadata is exported as GORUNANDCALLME_* env vars.
+type ExecConfig struct {
+	Command []string `yaml:"command"` // argv, e.g. ["./open-ticket.sh", "--queue", "sec"]
+	Format  string   `yaml:"format"`  // text | json (stdin payload)
+	Timeout string   `yaml:"timeout"` // default: 30s
+}
+
+// FileSinkConfig appends every notification to a local file.
+type FileSinkConfig struct {
+	Path   string `yaml:"path"`   // accepts {job_id}, {kind} and {state}
+	Format string `yaml:"format"` // text | jsonl
+}
+
+// SyslogConfig emits RFC 5424 messages (octet-counted framing over TCP/TLS).
+type SyslogConfig struct {
+	Network    string            `yaml:"network"`    // udp | tcp | tls | unix (default: unix)
+	Address    string            `yaml:"address"`    // host:port, or socket path for unix (default: /dev/log)
+	Facility   string            `yaml:"facility"`   // kern..local7 (default: local0)
+	AppName    string            `yaml:"app_name"`   // default: gorunandcallme
+	Hostname   string            `yaml:"hostname"`   // default: os.Hostname()
+	Severities map[string]string `yaml:"severities"` // per kind/state override, e.g. alert: crit, finished: notice
+	MaxBytes   int               `yaml:"max_bytes"`  // truncate messages (default: 8192 for udp, unlimited otherwise)
+}
+
+// RetentionConfig prunes old jobs on every run and rotates job logs.
+// Active jobs are never pruned.
+type RetentionConfig struct {
+	OlderThan      string   `yaml:"older_than"`       // prune jobs started before this, e.g. 30d
+	Statuses       []string `yaml:"statuses"`         // only prune these statuses (default: any ended job)
+	KeepLast       int      `yaml:"keep_last"`        // always keep the newest N jobs
+	MaxTotalSize   string   `yaml:"max_total_size"`   // prune oldest jobs while the jobs dir exceeds this, e.g. 10G
+	MaxLogSize     string   `yaml:"max_log_size"`     // rotate a job's output.log beyond this size, e.g. 100M
+	MaxLogSegments int      `yaml:"max_log_segments"` // gzip segments kept per job (default: 5)
+}
+
+// QueueConfig limits how many jobs of a --queue run at the same time.
+type QueueConfig struct {
+	MaxConcurrency int `yaml:"max_concurrency"` // default: 1
+}
+
+// QueueLimit returns the max concurrency of a queue (1 if unset).
+func (c *Config) QueueLimit(name string) int {
+	if q, ok := c.Queues[name]; ok && q.MaxConcurrency > 0 {
+		return q.MaxConcurrency
+	}
+	return 1
+}
+
+// ServerConfig configures the HTTP API of the serve command.
+type ServerConfig struct {
+	Listen    string `yaml:"listen"`     // default: 127.0.0.1:8787
+	Token     string `yaml:"token"`      // bearer token; required
+	ReadToken string `yaml:"read_token"` // optional, accepted on GET routes only
 }
 
 type CLIOverrides struct {
@@ -109,6 +303,11 @@ type CLIOverrides struct {
 	TelegramChatID    string
 	WebhookURL        string
 	WebhookHeaders    []string // KEY=VALUE
+	WebhookSecret     string
+	WebhookPayload    string
+
+	PagerDutyRoutingKey string
+	OpsgenieAPIKey      string
 }
 
 type LoadOptions struct {
@@ -128,6 +327,7 @@ func LoadMerged(o LoadOptions) (*Config, error) {
 			return nil, err
 		}
 		var fromFile Config
+		fromFile.Notify.Redaction.Defaults = cfg.Notify.Redaction.Defaults
 		if err := yaml.Unmarshal(raw, &fromFile); err != nil {
 			return nil, err
 		}
@@ -187,6 +387,18 @@ func applyCLIOverrides(cfg *Config, o CLIOverrides) {
 			}
 		}
 	}
+	if o.WebhookSecret != "" {
+		cfg.Webhook.Secret = o.WebhookSecret
+	}
+	if o.WebhookPayload != "" {
+		cfg.Webhook.Payload = o.WebhookPayload
+	}
+	if o.PagerDutyRoutingKey != "" {
+		cfg.PagerDuty.RoutingKey = o.PagerDutyRoutingKey
+	}
+	if o.OpsgenieAPIKey != "" {
+		cfg.Opsgenie.APIKey = o.OpsgenieAPIKey
+	}
 }
 
 func splitKV(s string) (string, string, bool) {
@@ -206,20 +418,38 @@ func mergeConfig(a Config, b Config) Config {
 	a.Transport = mergeTransport(a.Transport, b.Transport)
 	a.Notify = mergeNotify(a.Notify, b.Notify)
 
-	if b.Discord.WebhookURL != "" {
-		a.Discord = b.Discord
+	// The per-client merges only take fields b sets, so a profile can
+	// override a single field (e.g. pagerduty.severity) on its own.
+	a.Discord = mergeDiscord(a.Discord, b.Discord)
+	a.Slack = mergeSlack(a.Slack, b.Slack)
+	a.Telegram = mergeTelegram(a.Telegram, b.Telegram)
+	a.Webhook = mergeWebhook(a.Webhook, b.Webhook)
+	a.PagerDuty = mergePagerDuty(a.PagerDuty, b.PagerDuty)
+	a.Opsgenie = mergeOpsgenie(a.Opsgenie, b.Opsgenie)
+	a.MQTT = mergeMQTT(a.MQTT, b.MQTT)
+	a.NATS = mergeNATS(a.NATS, b.NATS)
+	a.Redis = mergeRedis(a.Redis, b.Redis)
+	a.Exec = mergeExec(a.Exec, b.Exec)
+	a.File = mergeFileSink(a.File, b.File)
+	a.Syslog = mergeSyslog(a.Syslog, b.Syslog)
+	if b.EventOutput != "" {
+		a.EventOutput = b.EventOutput
 	}
-	if b.Slack.WebhookURL != "" || b.Slack.BotToken != "" || b.Slack.Channel != "" {
-		a.Slack = mergeSlack(a.Slack, b.Slack)
+	if b.Retention.OlderThan != "" || len(b.Retention.Statuses) > 0 || b.Retention.KeepLast > 0 ||
+		b.Retention.MaxTotalSize != "" || b.Retention.MaxLogSize != "" || b.Retention.MaxLogSegments > 0 {
+		a.Retention = b.Retention
 	}
-	if b.Telegram.BotToken != "" || b.Telegram.ChatID != "" || b.Telegram.ParseMode != "" {
-		a.Telegram = mergeTelegram(a.Telegram, b.Telegram)
+	if b.Queues != nil {
+		a.Queues = b.Queues
 	}
-	if b.Webhook.URL != "" || len(b.Webhook.Headers) > 0 {
-		a.Webhook = mergeWebhook(a.Webhook, b.Webhook)
+	if b.Server.Listen != "" {
+		a.Server.Listen = b.Server.Listen
 	}
-	if b.EventOutput != "" {
-		a.EventOutput = b.EventOutput
+	if b.Server.Token != "" {
+		a.Server.Token = b.Server.Token
+	}
+	if b.Server.ReadToken != "" {
+		a.Server.ReadToken = b.Server.ReadToken
 	}
 
 	// profiles: replace if present
@@ -256,6 +486,25 @@ func mergeSlack(a, b SlackConfig) SlackConfig {
 	if b.Channel != "" {
 		a.Channel = b.Channel
 	}
+	if b.Interactive {
+		a.Interactive = true
+	}
+	if b.SigningSecret != "" {
+		a.SigningSecret = b.SigningSecret
+	}
+	return a
+}
+
+func mergeDiscord(a, b DiscordConfig) DiscordConfig {
+	if b.WebhookURL != "" {
+		a.WebhookURL = b.WebhookURL
+	}
+	if b.Interactive {
+		a.Interactive = true
+	}
+	if b.PublicKey != "" {
+		a.PublicKey = b.PublicKey
+	}
 	return a
 }
 
@@ -269,6 +518,12 @@ func mergeTelegram(a, b TelegramConfig) TelegramConfig {
 	if b.ParseMode != "" {
 		a.ParseMode = b.ParseMode
 	}
+	if b.APIURL != "" {
+		a.APIURL = b.APIURL
+	}
+	if len(b.AllowedChats) > 0 {
+		a.AllowedChats = b.AllowedChats
+	}
 	return a
 }
 
@@ -284,6 +539,169 @@ func mergeWebhook(a, b WebhookConfig) WebhookConfig {
 			a.Headers[k] = v
 		}
 	}
+	if b.Payload != "" {
+		a.Payload = b.Payload
+	}
+	if b.BodyTemplate != "" {
+		a.BodyTemplate = b.BodyTemplate
+	}
+	if b.Secret != "" {
+		a.Secret = b.Secret
+	}
+	if b.SignatureHeader != "" {
+		a.SignatureHeader = b.SignatureHeader
+	}
+	if b.TimestampHeader != "" {
+		a.TimestampHeader = b.TimestampHeader
+	}
+	return a
+}
+
+func mergePagerDuty(a, b PagerDutyConfig) PagerDutyConfig {
+	if b.RoutingKey != "" {
+		a.RoutingKey = b.RoutingKey
+	}
+	if b.EventsURL != "" {
+		a.EventsURL = b.EventsURL
+	}
+	if b.Source != "" {
+		a.Source = b.Source
+	}
+	if b.Severity != "" {
+		a.Severity = b.Severity
+	}
+	if b.ResolveOnFinish {
+		a.ResolveOnFinish = true
+	}
+	return a
+}
+
+func mergeOpsgenie(a, b OpsgenieConfig) OpsgenieConfig {
+	if b.APIKey != "" {
+		a.APIKey = b.APIKey
+	}
+	if b.APIURL != "" {
+		a.APIURL = b.APIURL
+	}
+	if b.Priority != "" {
+		a.Priority = b.Priority
+	}
+	if len(b.Tags) > 0 {
+		a.Tags = b.Tags
+	}
+	if b.ResolveOnFinish {
+		a.ResolveOnFinish = true
+	}
+	return a
+}
+
+func mergeMQTT(a, b MQTTConfig) MQTTConfig {
+	if b.URL != "" {
+		a.URL = b.URL
+	}
+	if b.Topic != "" {
+		a.Topic = b.Topic
+	}
+	if b.ClientID != "" {
+		a.ClientID = b.ClientID
+	}
+	if b.Username != "" {
+		a.Username = b.Username
+	}
+	if b.Password != "" {
+		a.Password = b.Password
+	}
+	if b.QoS != 0 {
+		a.QoS = b.QoS
+	}
+	if b.Retain {
+		a.Retain = true
+	}
+	return a
+}
+
+func mergeNATS(a, b NATSConfig) NATSConfig {
+	if b.URL != "" {
+		a.URL = b.URL
+	}
+	if b.Subject != "" {
+		a.Subject = b.Subject
+	}
+	if b.Token != "" {
+		a.Token = b.Token
+	}
+	if b.Username != "" {
+		a.Username = b.Username
+	}
+	if b.Password != "" {
+		a.Password = b.Password
+	}
+	return a
+}
+
+func mergeRedis(a, b RedisConfig) RedisConfig {
+	if b.URL != "" {
+		a.URL = b.URL
+	}
+	if b.Channel != "" {
+		a.Channel = b.Channel
+	}
+	if b.Password != "" {
+		a.Password = b.Password
+	}
+	return a
+}
+
+func mergeExec(a, b ExecConfig) ExecConfig {
+	if len(b.Command) > 0 {
+		a.Command = b.Command
+	}
+	if b.Format != "" {
+		a.Format = b.Format
+	}
+	if b.Timeout != "" {
+		a.Timeout = b.Timeout
+	}
+	return a
+}
+
+func mergeFileSink(a, b FileSinkConfig) FileSinkConfig {
+	if b.Path != "" {
+		a.Path = b.Path
+	}
+	if b.Format != "" {
+		a.Format = b.Format
+	}
+	return a
+}
+
+func mergeSyslog(a, b SyslogConfig) SyslogConfig {
+	if b.Network != "" {
+		a.Network = b.Network
+	}
+	if b.Address != "" {
+		a.Address = b.Address
+	}
+	if b.Facility != "" {
+		a.Facility = b.Facility
+	}
+	if b.AppName != "" {
+		a.AppName = b.AppName
+	}
+	if b.Hostname != "" {
+		a.Hostname = b.Hostname
+	}
+	if b.Severities != nil {
+		if a.Severities == nil {
+			a.Severities = map[string]string{}
+		}
+		for k, v := range b.Severities {
+			a.Severities[k] = v
+		}
+	}
+	if b.MaxBytes > 0 {
+		a.MaxBytes = b.MaxBytes
+	}
 	return a
 }
 
@@ -306,6 +724,24 @@ func mergeNotify(a, b NotifyConfig) NotifyConfig {
 	if b.StripProgress != "" {
 		a.StripProgress = b.StripProgress
 	}
+	if b.Heartbeat != "" {
+		a.Heartbeat = b.Heartbeat
+	}
+	if b.StallAfter != "" {
+		a.StallAfter = b.StallAfter
+	}
+	if b.KillOnStall {
+		a.KillOnStall = true
+	}
+	if len(b.NotifyOnExit) > 0 {
+		a.NotifyOnExit = b.NotifyOnExit
+	}
+	if b.QuietSuccess {
+		a.QuietSuccess = true
+	}
+	if b.FailureLines != 0 {
+		a.FailureLines = b.FailureLines
+	}
 
 	// Text
 	if b.Text.Select != "" {
@@ -373,6 +809,18 @@ func mergeAlerts(a, b AlertsConfig) AlertsConfig {
 	if b.IncludeContextLines != 0 {
 		a.IncludeContextLines = b.IncludeContextLines
 	}
+	if len(b.Rules) > 0 {
+		a.Rules = b.Rules
+	}
+	if b.Cooldown != "" {
+		a.Cooldown = b.Cooldown
+	}
+	if b.DedupWindow != "" {
+		a.DedupWindow = b.DedupWindow
+	}
+	if b.MaxPerHour != 0 {
+		a.MaxPerHour = b.MaxPerHour
+	}
 	return a
 }
 
@@ -386,12 +834,29 @@ func (c *Config) Clone() *Config {
 	out.Notify.Filters.Exclude = append([]string{}, c.Notify.Filters.Exclude...)
 	out.Notify.Redaction.Patterns = append([]string{}, c.Notify.Redaction.Patterns...)
 	out.Notify.Alerts.Patterns = append([]string{}, c.Notify.Alerts.Patterns...)
+	out.Notify.Alerts.Rules = append([]AlertRule{}, c.Notify.Alerts.Rules...)
 	if c.Webhook.Headers != nil {
 		out.Webhook.Headers = map[string]string{}
 		for k, v := range c.Webhook.Headers {
 			out.Webhook.Headers[k] = v
 		}
 	}
+	if c.Syslog.Severities != nil {
+		out.Syslog.Severities = map[string]string{}
+		for k, v := range c.Syslog.Severities {
+			out.Syslog.Severities[k] = v
+		}
+	}
+	out.Opsgenie.Tags = append([]string{}, c.Opsgenie.Tags...)
+	out.Telegram.AllowedChats = append([]string{}, c.Telegram.AllowedChats...)
+	out.Exec.Command = append([]string{}, c.Exec.Command...)
+	out.Retention.Statuses = append([]string{}, c.Retention.Statuses...)
+	if c.Queues != nil {
+		out.Queues = map[string]QueueConfig{}
+		for k, v := range c.Queues {
+			out.Queues[k] = v
+		}
+	}
 	if c.Notify.Attach.PartMaxBytes != nil {
 		out.Notify.Attach.PartMaxBytes = map[string]int{}
 		for k, v := range c.Notify.Attach.PartMaxBytes {
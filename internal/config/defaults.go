@@ -55,6 +55,12 @@ func DefaultConfig() *Config {
 		Webhook: WebhookConfig{
 			Headers: map[string]string{},
 		},
+		PagerDuty: PagerDutyConfig{
+			Severity: "critical",
+		},
+		Opsgenie: OpsgenieConfig{
+			Priority: "P3",
+		},
 		EventOutput: "",
 		Profiles:    map[string]*ProfileConfig{},
 	}
@@ -70,16 +76,40 @@ func MergeBaseWithProfile(base *Config, p *ProfileConfig) *Config {
 		out.Notify = util.Merge(out.Notify, *p.Notify)
 	}
 	if p.Discord != nil {
-		out.Discord = util.Merge(out.Discord, *p.Discord)
+		out.Discord = mergeDiscord(out.Discord, *p.Discord)
 	}
 	if p.Slack != nil {
-		out.Slack = util.Merge(out.Slack, *p.Slack)
+		out.Slack = mergeSlack(out.Slack, *p.Slack)
 	}
 	if p.Telegram != nil {
-		out.Telegram = util.Merge(out.Telegram, *p.Telegram)
+		out.Telegram = mergeTelegram(out.Telegram, *p.Telegram)
 	}
 	if p.Webhook != nil {
-		out.Webhook = util.Merge(out.Webhook, *p.Webhook)
+		out.Webhook = mergeWebhook(out.Webhook, *p.Webhook)
+	}
+	if p.PagerDuty != nil {
+		out.PagerDuty = mergePagerDuty(out.PagerDuty, *p.PagerDuty)
+	}
+	if p.Opsgenie != nil {
+		out.Opsgenie = mergeOpsgenie(out.Opsgenie, *p.Opsgenie)
+	}
+	if p.MQTT != nil {
+		out.MQTT = mergeMQTT(out.MQTT, *p.MQTT)
+	}
+	if p.NATS != nil {
+		out.NATS = mergeNATS(out.NATS, *p.NATS)
+	}
+	if p.Redis != nil {
+		out.Redis = mergeRedis(out.Redis, *p.Redis)
+	}
+	if p.Exec != nil {
+		out.Exec = mergeExec(out.Exec, *p.Exec)
+	}
+	if p.File != nil {
+		out.File = mergeFileSink(out.File, *p.File)
+	}
+	if p.Syslog != nil {
+		out.Syslog = mergeSyslog(out.Syslog, *p.Syslog)
 	}
 
 	return out
@@ -0,0 +1,137 @@
+// Package control lets chat users act on jobs remotely: a Telegram bot
+// command listener and the interaction receiver for Slack and Discord
+// buttons. Both map onto the job package commands.
+package control
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/haltman-io/gorunandcallme/internal/job"
+	"github.com/haltman-io/gorunandcallme/internal/notify"
+	"github.com/haltman-io/gorunandcallme/internal/util"
+)
+
+const (
+	defaultTailLines = 20
+	maxTailLines     = 200
+	maxListJobs      = 15
+)
+
+// Jobs runs job commands on behalf of a chat user and returns the text to
+// reply with.
+type Jobs struct {
+	Store *job.Store
+
+	// RunnerPath is the runner executable used by /rerun (usually os.Executable()).
+	RunnerPath string
+
+	// Spawned is called after /rerun created a job, e.g. to make sure a
+	// supervisor starts it when it was queued. Optional.
+	Spawned func(res job.SpawnResult)
+
+	// Stop controls how /stop terminates jobs (default SIGTERM, 10s grace).
+	Stop job.StopOptions
+
+	// Redact is applied to log lines sent to chats, like to notifications.
+	// Optional.
+	Redact *notify.Redactor
+}
+
+// List renders the newest jobs, one short line each.
+func (j *Jobs) List(statuses []string) (string, error) {
+	st, err := job.ParseStatuses(statuses)
+	if err != nil {
+		return "", err
+	}
+	jobs, err := job.ListJobs(j.Store, job.ListOptions{Statuses: st, Limit: maxListJobs})
+	if err != nil {
+		return "", err
+	}
+	if len(jobs) == 0 {
+		return "no jobs", nil
+	}
+	var b strings.Builder
+	tw := tabwriter.NewWriter(&b, 0, 0, 1, ' ', 0)
+	for i, m := range jobs {
+		status := string(m.Status)
+		if m.Status == job.StatusQueued && m.QueuePosition > 0 {
+			status = fmt.Sprintf("queued#%d", m.QueuePosition)
+		}
+		if m.ExitCode != nil {
+			status += fmt.Sprintf("(%d)", *m.ExitCode)
+		}
+		what := m.Name
+		if what == "" {
+			what = shorten(m.CommandLine(), 40)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", i+1, status, formatElapsed(m.Duration()), what)
+	}
+	_ = tw.Flush()
+	return b.String(), nil
+}
+
+func (j *Jobs) Status(ref string) (string, error) {
+	var b strings.Builder
+	err := job.CmdStatus(&b, j.Store, ref)
+	return b.String(), err
+}
+
+// Tail returns the last n lines of a job log (default 20, at most 200),
+// without ANSI sequences and redacted.
+func (j *Jobs) Tail(ref string, n int) (string, error) {
+	if n <= 0 {
+		n = defaultTailLines
+	}
+	n = min(n, maxTailLines)
+	id, err := job.ResolveJobRef(j.Store, ref)
+	if err != nil {
+		return "", err
+	}
+	m, err := j.Store.ReadMeta(id)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := job.TailFile(&b, m.LogPath, n); err != nil {
+		return "", fmt.Errorf("tail %s: %w", id, err)
+	}
+	out := j.Redact.Apply(util.StripANSI(b.String()))
+	if strings.TrimSpace(out) == "" {
+		return fmt.Sprintf("%s: no output yet", id), nil
+	}
+	return out, nil
+}
+
+func (j *Jobs) StopJob(ref string) (string, error) {
+	var b strings.Builder
+	err := job.CmdStop(&b, j.Store, ref, j.Stop)
+	return b.String(), err
+}
+
+func (j *Jobs) Rerun(ref string) (string, error) {
+	var b strings.Builder
+	res, err := job.CmdRerun(context.Background(), &b, j.Store, ref, job.RerunOptions{RunnerPath: j.RunnerPath})
+	if err == nil && j.Spawned != nil {
+		j.Spawned(res)
+	}
+	return b.String(), err
+}
+
+func shorten(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+func formatElapsed(d time.Duration) string {
+	if d <= 0 {
+		return "-"
+	}
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,433 @@
+package control
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haltman-io/gorunandcallme/internal/job"
+	"github.com/haltman-io/gorunandcallme/internal/notify"
+	"github.com/haltman-io/gorunandcallme/internal/util"
+)
+
+const (
+	defaultMuteFor     = time.Hour
+	maxRequestAge      = 5 * time.Minute
+	maxInteractionBody = 1 << 20
+	logTailFallback    = 40 // lines posted when the log cannot be uploaded
+	defaultDiscordAPI  = "https://discord.com/api/v10"
+)
+
+type InteractionOptions struct {
+	Jobs *Jobs
+
+	// SlackSigningSecret enables POST /slack/interactions.
+	SlackSigningSecret string
+	// SlackBotToken uploads logs as files ("Send full log"); without it the
+	// log tail is posted as text.
+	SlackBotToken string
+
+	// DiscordPublicKey (hex) enables POST /discord/interactions.
+	DiscordPublicKey string
+	DiscordAPIURL    string // default https://discord.com/api/v10
+
+	MuteFor time.Duration // default 1h
+	HTTP    *http.Client
+	Log     io.Writer // default io.Discard
+}
+
+// Interactions receives button clicks from Slack and Discord messages (see
+// notify.SlackClient.SendMessage and notify.DiscordClient.SendMessage),
+// verifies their signatures and runs the action on the job store.
+type Interactions struct {
+	opt        InteractionOptions
+	discordKey ed25519.PublicKey
+	mux        *http.ServeMux
+}
+
+func NewInteractions(opt InteractionOptions) (*Interactions, error) {
+	if opt.Jobs == nil || opt.Jobs.Store == nil {
+		return nil, errors.New("interactions: job store is required")
+	}
+	if opt.SlackSigningSecret == "" && opt.DiscordPublicKey == "" {
+		return nil, errors.New("interactions: set slack.signing_secret and/or discord.public_key")
+	}
+	if opt.DiscordAPIURL == "" {
+		opt.DiscordAPIURL = defaultDiscordAPI
+	}
+	if opt.MuteFor <= 0 {
+		opt.MuteFor = defaultMuteFor
+	}
+	if opt.HTTP == nil {
+		opt.HTTP = &http.Client{Timeout: 30 * time.Second}
+	}
+	if opt.Log == nil {
+		opt.Log = io.Discard
+	}
+
+	h := &Interactions{opt: opt, mux: http.NewServeMux()}
+	if opt.SlackSigningSecret != "" {
+		h.mux.HandleFunc("POST /slack/interactions", h.handleSlack)
+	}
+	if opt.DiscordPublicKey != "" {
+		key, err := hex.DecodeString(strings.TrimSpace(opt.DiscordPublicKey))
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, errors.New("interactions: discord public_key must be a hex ed25519 key")
+		}
+		h.discordKey = key
+		h.mux.HandleFunc("POST /discord/interactions", h.handleDiscord)
+	}
+	return h, nil
+}
+
+func (h *Interactions) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// result is the outcome of a button click.
+type result struct {
+	Text string
+	Log  *job.Meta // "Send full log": the job whose log to send
+}
+
+// run executes action on jobID for user.
+func (h *Interactions) run(action, jobID, user string) result {
+	fmt.Fprintf(h.opt.Log, "interactions: %s %s by %s\n", action, jobID, user)
+	j := h.opt.Jobs
+	id, err := job.ResolveJobRef(j.Store, jobID)
+	if err != nil {
+		return result{Text: "error: " + err.Error()}
+	}
+	jobID = id
+	switch action {
+	case notify.ActionStop:
+		out, err := j.StopJob(jobID)
+		if err != nil {
+			return result{Text: strings.TrimSpace(out + "\nerror: " + err.Error())}
+		}
+		return result{Text: strings.TrimSpace(out) + " (by " + user + ")"}
+	case notify.ActionMute:
+		until := time.Now().Add(h.opt.MuteFor)
+		if err := j.Store.MuteAlerts(jobID, until); err != nil {
+			return result{Text: "error: " + err.Error()}
+		}
+		return result{Text: fmt.Sprintf("alerts of %s muted until %s (by %s)", jobID, until.Format("15:04 MST"), user)}
+	case notify.ActionSendLog:
+		m, err := j.Store.ReadMeta(jobID)
+		if err != nil {
+			return result{Text: "error: " + err.Error()}
+		}
+		return result{Text: "log of " + jobID, Log: &m}
+	}
+	return result{Text: "unknown action " + action}
+}
+
+// readLog returns the job log for an upload of at most limit bytes, redacted;
+// longer logs are cut from the front. Only the tail is kept in memory: when
+// the live file alone is long enough the rotated segments are not read.
+func (h *Interactions) readLog(m job.Meta, limit int) ([]byte, error) {
+	var r io.Reader
+	cut := false
+	if fi, err := os.Stat(m.LogPath); err == nil && limit > 0 && fi.Size() >= int64(limit) {
+		cut = fi.Size() > int64(limit)
+		f, err := os.Open(m.LogPath)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		if _, err := f.Seek(-int64(limit), io.SeekEnd); err != nil {
+			return nil, err
+		}
+		r = f
+	} else {
+		rc, err := job.OpenFullLog(m.LogPath)
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		r = rc
+	}
+	b, truncated, err := readTail(r, limit)
+	if err != nil {
+		return nil, err
+	}
+	if truncated || cut {
+		if i := bytes.IndexByte(b, '\n'); i >= 0 {
+			b = b[i+1:]
+		}
+	}
+	return []byte(h.opt.Jobs.Redact.Apply(util.StripANSI(string(b)))), nil
+}
+
+// readTail reads r to the end and returns its last limit bytes (all of it
+// when limit <= 0), buffering at most 2*limit bytes.
+func readTail(r io.Reader, limit int) ([]byte, bool, error) {
+	if limit <= 0 {
+		b, err := io.ReadAll(r)
+		return b, false, err
+	}
+	buf := make([]byte, 0, 2*limit)
+	chunk := make([]byte, min(limit, 32*1024))
+	truncated := false
+	for {
+		n, err := r.Read(chunk)
+		if len(buf)+n > cap(buf) {
+			drop := len(buf) + n - limit
+			buf = append(buf[:0], buf[drop:]...)
+			truncated = true
+		}
+		buf = append(buf, chunk[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	if len(buf) > limit {
+		buf = buf[len(buf)-limit:]
+		truncated = true
+	}
+	return buf, truncated, nil
+}
+
+func (h *Interactions) logTail(m job.Meta) string {
+	var b strings.Builder
+	if err := job.TailFile(&b, m.LogPath, logTailFallback); err != nil {
+		return "error: " + err.Error()
+	}
+	return notify.WrapCodeBlockMarkdown(h.opt.Jobs.Redact.Apply(util.StripANSI(b.String())))
+}
+
+// readBody reads a request body that is verified before it is parsed.
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxInteractionBody))
+	if err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return nil, false
+	}
+	return body, true
+}
+
+// freshTimestamp rejects replayed requests.
+func freshTimestamp(ts string) bool {
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return false
+	}
+	age := time.Since(time.Unix(sec, 0))
+	return age < maxRequestAge && age > -maxRequestAge
+}
+
+// ---- Slack ----
+
+type slackPayload struct {
+	Type string `json:"type"`
+	User struct {
+		Username string `json:"username"`
+		Name     string `json:"name"`
+	} `json:"user"`
+	Channel struct {
+		ID string `json:"id"`
+	} `json:"channel"`
+	Actions []struct {
+		ActionID string `json:"action_id"`
+		Value    string `json:"value"`
+	} `json:"actions"`
+	ResponseURL string `json:"response_url"`
+}
+
+// verifySlack checks the v0 request signature:
+// "v0=" + hex(HMAC-SHA256(secret, "v0:" + timestamp + ":" + body)).
+func (h *Interactions) verifySlack(r *http.Request, body []byte) bool {
+	ts := r.Header.Get("X-Slack-Request-Timestamp")
+	sig := r.Header.Get("X-Slack-Signature")
+	if !freshTimestamp(ts) || !strings.HasPrefix(sig, "v0=") {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(h.opt.SlackSigningSecret))
+	mac.Write([]byte("v0:" + ts + ":"))
+	mac.Write(body)
+	want := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(sig), []byte(want))
+}
+
+func (h *Interactions) handleSlack(w http.ResponseWriter, r *http.Request) {
+	body, ok := readBody(w, r)
+	if !ok {
+		return
+	}
+	if !h.verifySlack(r, body) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	var p slackPayload
+	if err := json.Unmarshal([]byte(r.PostForm.Get("payload")), &p); err != nil {
+		http.Error(w, "bad payload", http.StatusBadRequest)
+		return
+	}
+	// Slack wants an answer within 3s; the action reports via response_url.
+	w.WriteHeader(http.StatusOK)
+	if p.Type != "block_actions" || len(p.Actions) == 0 {
+		return
+	}
+	user := p.User.Username
+	if user == "" {
+		user = p.User.Name
+	}
+	a := p.Actions[0]
+	go h.slackAction(p, a.ActionID, a.Value, user)
+}
+
+func (h *Interactions) slackAction(p slackPayload, action, jobID, user string) {
+	res := h.run(action, jobID, user)
+	text := res.Text
+	if res.Log != nil {
+		if h.opt.SlackBotToken != "" && p.Channel.ID != "" {
+			err := h.slackUpload(p.Channel.ID, *res.Log)
+			if err == nil {
+				return
+			}
+			fmt.Fprintf(h.opt.Log, "interactions: slack upload: %v\n", err)
+		}
+		text = "last lines of " + jobID + ":\n" + h.logTail(*res.Log)
+	}
+	if p.ResponseURL == "" {
+		return
+	}
+	b, _ := json.Marshal(map[string]any{
+		"response_type":    "in_channel",
+		"replace_original": false,
+		"text":             text,
+	})
+	resp, err := h.opt.HTTP.Post(p.ResponseURL, "application/json", bytes.NewReader(b))
+	if err != nil {
+		fmt.Fprintf(h.opt.Log, "interactions: slack response: %v\n", err)
+		return
+	}
+	resp.Body.Close()
+}
+
+func (h *Interactions) slackUpload(channel string, m job.Meta) error {
+	c, err := notify.NewSlackClient(h.opt.HTTP, "", h.opt.SlackBotToken, channel, false)
+	if err != nil {
+		return err
+	}
+	data, err := h.readLog(m, c.MaxAttachBytes())
+	if err != nil {
+		return err
+	}
+	return c.SendFile(m.ID+".log", "text/plain", data, "Full log of "+m.ID)
+}
+
+// ---- Discord ----
+
+type discordInteraction struct {
+	Type          int    `json:"type"` // 1 PING, 3 MESSAGE_COMPONENT
+	ApplicationID string `json:"application_id"`
+	Token         string `json:"token"`
+	Data          struct {
+		CustomID string `json:"custom_id"`
+	} `json:"data"`
+	Member *struct {
+		User discordUser `json:"user"`
+	} `json:"member"`
+	User *discordUser `json:"user"`
+}
+
+type discordUser struct {
+	Username string `json:"username"`
+}
+
+// verifyDiscord checks the Ed25519 signature of timestamp + body.
+func (h *Interactions) verifyDiscord(r *http.Request, body []byte) bool {
+	ts := r.Header.Get("X-Signature-Timestamp")
+	sig, err := hex.DecodeString(r.Header.Get("X-Signature-Ed25519"))
+	if err != nil || len(sig) != ed25519.SignatureSize || !freshTimestamp(ts) {
+		return false
+	}
+	return ed25519.Verify(h.discordKey, append([]byte(ts), body...), sig)
+}
+
+func (h *Interactions) handleDiscord(w http.ResponseWriter, r *http.Request) {
+	body, ok := readBody(w, r)
+	if !ok {
+		return
+	}
+	if !h.verifyDiscord(r, body) {
+		http.Error(w, "invalid request signature", http.StatusUnauthorized)
+		return
+	}
+	var in discordInteraction
+	if err := json.Unmarshal(body, &in); err != nil {
+		http.Error(w, "bad payload", http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	switch in.Type {
+	case 1: // PING
+		_, _ = w.Write([]byte(`{"type":1}`))
+		return
+	case 3:
+	default:
+		http.Error(w, "unsupported interaction", http.StatusBadRequest)
+		return
+	}
+	action, jobID, ok := notify.ParseControlID(in.Data.CustomID)
+	if !ok {
+		http.Error(w, "unknown component", http.StatusBadRequest)
+		return
+	}
+	user := "unknown"
+	if in.Member != nil {
+		user = in.Member.User.Username
+	} else if in.User != nil {
+		user = in.User.Username
+	}
+	// Answer within 3s with a deferred reply; the result is a follow-up.
+	_, _ = w.Write([]byte(`{"type":5}`))
+	go h.discordAction(in, action, jobID, user)
+}
+
+func (h *Interactions) discordAction(in discordInteraction, action, jobID, user string) {
+	res := h.run(action, jobID, user)
+	hook := fmt.Sprintf("%s/webhooks/%s/%s", strings.TrimRight(h.opt.DiscordAPIURL, "/"), in.ApplicationID, in.Token)
+	c, err := notify.NewDiscordClient(h.opt.HTTP, hook, 0, false)
+	if err != nil {
+		fmt.Fprintf(h.opt.Log, "interactions: discord follow-up: %v\n", err)
+		return
+	}
+	if res.Log != nil {
+		data, err := h.readLog(*res.Log, c.MaxAttachBytes())
+		if err == nil {
+			err = c.SendFile(jobID+".log", "text/plain", data, "Full log of "+jobID)
+		}
+		if err == nil {
+			return
+		}
+		fmt.Fprintf(h.opt.Log, "interactions: discord upload: %v\n", err)
+		res.Text = "last lines of " + jobID + ":\n" + h.logTail(*res.Log)
+	}
+	if r := []rune(res.Text); len(r) > c.MaxTextChars() {
+		res.Text = string(r[len(r)-c.MaxTextChars():])
+	}
+	if err := c.SendText(res.Text); err != nil {
+		fmt.Fprintf(h.opt.Log, "interactions: discord follow-up: %v\n", err)
+	}
+}
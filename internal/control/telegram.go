@@ -0,0 +1,265 @@
+package control
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haltman-io/gorunandcallme/internal/notify"
+)
+
+const (
+	telegramPollTimeout = 30 * time.Second
+	telegramRetryDelay  = 5 * time.Second
+	telegramMaxReply    = 3800 // below the 4096 message limit
+)
+
+type TelegramOptions struct {
+	APIURL   string // default notify.DefaultTelegramAPIURL
+	BotToken string
+
+	// AllowedChats are the chat IDs whose commands are executed; others are
+	// ignored without a reply.
+	AllowedChats []string
+
+	Jobs *Jobs
+	HTTP *http.Client // default: times out shortly after the long poll
+	Log  io.Writer    // default io.Discard
+}
+
+// TelegramBot long-polls getUpdates and answers job commands:
+//
+//	/jobs [status...]   newest jobs
+//	/status <job>       job status
+//	/tail <job> [n]     last n log lines (default 20)
+//	/stop <job>         stop a running job
+//	/rerun <job>        start the job again
+type TelegramBot struct {
+	opt     TelegramOptions
+	allowed map[int64]bool
+	offset  int64
+	started int64 // unix time Run started; older commands are skipped
+}
+
+func NewTelegramBot(opt TelegramOptions) (*TelegramBot, error) {
+	if opt.BotToken == "" {
+		return nil, errors.New("telegram: bot_token is required")
+	}
+	if opt.Jobs == nil || opt.Jobs.Store == nil {
+		return nil, errors.New("telegram: job store is required")
+	}
+	if opt.HTTP == nil {
+		opt.HTTP = &http.Client{Timeout: telegramPollTimeout + 15*time.Second}
+	}
+	if opt.Log == nil {
+		opt.Log = io.Discard
+	}
+	b := &TelegramBot{opt: opt, allowed: map[int64]bool{}}
+	for _, c := range opt.AllowedChats {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(c, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("telegram: invalid chat id %q", c)
+		}
+		b.allowed[id] = true
+	}
+	if len(b.allowed) == 0 {
+		return nil, errors.New("telegram: no allowed chats (set telegram.allowed_chats or chat_id)")
+	}
+	return b, nil
+}
+
+type tgUpdate struct {
+	UpdateID int64      `json:"update_id"`
+	Message  *tgMessage `json:"message"`
+}
+
+type tgMessage struct {
+	MessageID int64  `json:"message_id"`
+	Date      int64  `json:"date"`
+	Text      string `json:"text"`
+	Chat      struct {
+		ID int64 `json:"id"`
+	} `json:"chat"`
+}
+
+type tgResponse struct {
+	OK          bool            `json:"ok"`
+	Description string          `json:"description"`
+	Result      json.RawMessage `json:"result"`
+}
+
+// Run polls for commands until ctx is done. The offset lives in memory only,
+// so commands sent before Run started (e.g. while the bot was restarting)
+// are skipped instead of being run late.
+func (b *TelegramBot) Run(ctx context.Context) error {
+	b.started = time.Now().Unix()
+	fmt.Fprintf(b.opt.Log, "telegram: listening for commands from %d chat(s)\n", len(b.allowed))
+	for {
+		updates, err := b.getUpdates(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintf(b.opt.Log, "telegram: getUpdates: %v\n", err)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(telegramRetryDelay):
+			}
+			continue
+		}
+		for _, u := range updates {
+			b.offset = u.UpdateID + 1
+			if u.Message == nil || !strings.HasPrefix(u.Message.Text, "/") {
+				continue
+			}
+			if !b.allowed[u.Message.Chat.ID] {
+				fmt.Fprintf(b.opt.Log, "telegram: ignoring command from chat %d\n", u.Message.Chat.ID)
+				continue
+			}
+			if u.Message.Date < b.started {
+				fmt.Fprintf(b.opt.Log, "telegram: skipping command sent before start: %s\n", u.Message.Text)
+				continue
+			}
+			reply := b.handle(u.Message.Text)
+			if err := b.sendMessage(ctx, u.Message.Chat.ID, u.Message.MessageID, reply); err != nil {
+				fmt.Fprintf(b.opt.Log, "telegram: sendMessage: %v\n", err)
+			}
+		}
+	}
+}
+
+// handle runs one command and returns the reply text.
+func (b *TelegramBot) handle(text string) string {
+	fields := strings.Fields(text)
+	cmd, _, _ := strings.Cut(fields[0], "@") // "/jobs@my_bot" in groups
+	args := fields[1:]
+	j := b.opt.Jobs
+
+	needRef := func() (string, bool) {
+		if len(args) == 0 {
+			return "", false
+		}
+		return args[0], true
+	}
+
+	var out string
+	var err error
+	switch strings.ToLower(cmd) {
+	case "/start", "/help":
+		return telegramHelp
+	case "/jobs":
+		out, err = j.List(args)
+	case "/status":
+		ref, ok := needRef()
+		if !ok {
+			return "usage: /status <job>"
+		}
+		out, err = j.Status(ref)
+	case "/tail":
+		ref, ok := needRef()
+		if !ok {
+			return "usage: /tail <job> [lines]"
+		}
+		n := 0
+		if len(args) > 1 {
+			if n, err = strconv.Atoi(args[1]); err != nil || n <= 0 {
+				return "usage: /tail <job> [lines]"
+			}
+		}
+		out, err = j.Tail(ref, n)
+	case "/stop":
+		ref, ok := needRef()
+		if !ok {
+			return "usage: /stop <job>"
+		}
+		out, err = j.StopJob(ref)
+	case "/rerun":
+		ref, ok := needRef()
+		if !ok {
+			return "usage: /rerun <job>"
+		}
+		out, err = j.Rerun(ref)
+	default:
+		return "unknown command " + cmd + "\n\n" + telegramHelp
+	}
+	if err != nil {
+		out = strings.TrimSpace(out + "\nerror: " + err.Error())
+	}
+	return out
+}
+
+const telegramHelp = `/jobs [status...] - newest jobs
+/status <job> - job status
+/tail <job> [n] - last n log lines
+/stop <job> - stop a running job
+/rerun <job> - start a job again
+
+<job> is an ID, a /jobs index or a job name.`
+
+func (b *TelegramBot) getUpdates(ctx context.Context) ([]tgUpdate, error) {
+	body, _ := json.Marshal(map[string]any{
+		"offset":          b.offset,
+		"timeout":         int(telegramPollTimeout / time.Second),
+		"allowed_updates": []string{"message"},
+	})
+	var updates []tgUpdate
+	if err := b.call(ctx, "getUpdates", body, &updates); err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
+
+// sendMessage replies as plain text, keeping the end of long output.
+func (b *TelegramBot) sendMessage(ctx context.Context, chatID, replyTo int64, text string) error {
+	if strings.TrimSpace(text) == "" {
+		text = "ok"
+	}
+	if r := []rune(text); len(r) > telegramMaxReply {
+		text = "…" + string(r[len(r)-telegramMaxReply:])
+	}
+	body, _ := json.Marshal(map[string]any{
+		"chat_id":                  chatID,
+		"text":                     text,
+		"reply_to_message_id":      replyTo,
+		"disable_web_page_preview": true,
+	})
+	return b.call(ctx, "sendMessage", body, nil)
+}
+
+func (b *TelegramBot) call(ctx context.Context, method string, body []byte, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		notify.TelegramMethodURL(b.opt.APIURL, b.opt.BotToken, method), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := b.opt.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var r tgResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 8<<20)).Decode(&r); err != nil {
+		return fmt.Errorf("%s: %s", method, resp.Status)
+	}
+	if !r.OK {
+		return fmt.Errorf("%s: %s", method, r.Description)
+	}
+	if result != nil {
+		return json.Unmarshal(r.Result, result)
+	}
+	return nil
+}
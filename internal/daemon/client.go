@@ -0,0 +1,78 @@
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"time"
+
+	"github.com/haltman-io/gorunandcallme/internal/job"
+)
+
+// Client talks to a running daemon.
+type Client struct {
+	rpc *rpc.Client
+}
+
+// Dial connects to the daemon serving stateDir. It fails fast when none is
+// running, so callers can fall back to the file store.
+func Dial(stateDir string) (*Client, error) {
+	conn, err := net.DialTimeout("unix", SocketPath(stateDir), time.Second)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{rpc: jsonrpc.NewClient(conn)}
+	var pong PingReply
+	if err := c.rpc.Call("Jobs.Ping", Empty{}, &pong); err != nil {
+		_ = c.Close()
+		return nil, fmt.Errorf("daemon: ping: %w", err)
+	}
+	return c, nil
+}
+
+func (c *Client) Close() error {
+	return c.rpc.Close()
+}
+
+func (c *Client) List(opt job.ListOptions) ([]job.Meta, error) {
+	var out []job.Meta
+	err := c.rpc.Call("Jobs.List", ListArgs{Options: opt}, &out)
+	return out, err
+}
+
+func (c *Client) Status(ref string) (string, error) {
+	var out Output
+	err := c.rpc.Call("Jobs.Status", RefArgs{Ref: ref}, &out)
+	return out.Text, err
+}
+
+func (c *Client) Start(args StartArgs) (job.SpawnResult, error) {
+	var out job.SpawnResult
+	err := c.rpc.Call("Jobs.Start", args, &out)
+	return out, err
+}
+
+func (c *Client) Stop(ref string, opt job.StopOptions) (string, error) {
+	var out Output
+	err := c.rpc.Call("Jobs.Stop", StopArgs{Ref: ref, Options: opt}, &out)
+	return out.Text, err
+}
+
+func (c *Client) Cancel(ref string) (string, error) {
+	var out Output
+	err := c.rpc.Call("Jobs.Cancel", RefArgs{Ref: ref}, &out)
+	return out.Text, err
+}
+
+func (c *Client) Purge(ref string) (string, error) {
+	var out Output
+	err := c.rpc.Call("Jobs.Purge", RefArgs{Ref: ref}, &out)
+	return out.Text, err
+}
+
+func (c *Client) Rerun(args RerunArgs) (RerunReply, error) {
+	var out RerunReply
+	err := c.rpc.Call("Jobs.Rerun", args, &out)
+	return out, err
+}
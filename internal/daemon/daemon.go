@@ -0,0 +1,137 @@
+// Package daemon implements the optional long-running job daemon. It owns job
+// spawning and reaping, runs the queue/schedule supervisor and answers job
+// commands over JSON-RPC on a unix socket in the state dir. Without a daemon,
+// every command works directly on the file store.
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/haltman-io/gorunandcallme/internal/job"
+	"github.com/haltman-io/gorunandcallme/internal/schedule"
+)
+
+const SocketFileName = "daemon.sock"
+
+// SocketPath is the control socket of the daemon serving stateDir.
+func SocketPath(stateDir string) string {
+	return filepath.Join(stateDir, SocketFileName)
+}
+
+type Options struct {
+	// RunnerPath is the runner executable used for every job (usually os.Executable()).
+	RunnerPath string
+
+	// Limit returns the max concurrency of a job queue.
+	Limit func(queue string) int
+
+	Log io.Writer // default io.Discard
+}
+
+// Serve runs the daemon until ctx is done. A standalone supervisor that is
+// already running is asked to exit first; the daemon takes over its queues
+// and schedules.
+func Serve(ctx context.Context, st *job.Store, opt Options) error {
+	if opt.Log == nil {
+		opt.Log = io.Discard
+	}
+	sock := SocketPath(st.BaseDir)
+	sst, err := schedule.NewStore(st.BaseDir)
+	if err != nil {
+		return err
+	}
+
+	// Take the supervisor lock before touching the socket: it keeps a second
+	// daemon, or a supervisor started by "job run --queue" meanwhile, from
+	// racing this one.
+	release, err := lockDaemon(st, sock)
+	if err != nil {
+		return err
+	}
+	defer release()
+	_ = os.Remove(sock) // stale socket of a crashed daemon
+
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		return fmt.Errorf("daemon: listen: %w", err)
+	}
+	defer func() { _ = os.Remove(sock) }()
+	defer func() { _ = ln.Close() }()
+	_ = os.Chmod(sock, 0o600)
+
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("Jobs", &Jobs{st: st, runnerPath: opt.RunnerPath}); err != nil {
+		return fmt.Errorf("daemon: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	supervised := make(chan error, 1)
+	go func() {
+		supervised <- job.Supervise(ctx, st, job.SuperviseOptions{
+			Locked: true,
+			Limit:  opt.Limit,
+			Tick: func(now time.Time) bool {
+				schedule.Tick(st, sst, now, opt.Log)
+				return true // the daemon never idles out
+			},
+			Log: opt.Log,
+		})
+	}()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go srv.ServeCodec(jsonrpc.NewServerCodec(conn))
+		}
+	}()
+
+	fmt.Fprintf(opt.Log, "daemon: listening on %s (pid %d)\n", sock, os.Getpid())
+
+	select {
+	case <-ctx.Done():
+		fmt.Fprintln(opt.Log, "daemon: shutting down")
+		<-supervised
+		return nil
+	case err := <-supervised:
+		if err == nil && ctx.Err() == nil {
+			err = errors.New("daemon: supervisor exited")
+		}
+		return err
+	}
+}
+
+// lockDaemon takes the supervisor lock, stopping a standalone supervisor
+// that holds it. It fails if a daemon already answers on sock.
+func lockDaemon(st *job.Store, sock string) (func(), error) {
+	for attempt := 0; ; attempt++ {
+		if c, err := Dial(st.BaseDir); err == nil {
+			_ = c.Close()
+			return nil, fmt.Errorf("daemon: already running (%s)", sock)
+		}
+		release, err := job.LockSupervisor(st)
+		if err == nil {
+			return release, nil
+		}
+		if !errors.Is(err, fs.ErrExist) || attempt == 2 {
+			return nil, fmt.Errorf("daemon: %w", err)
+		}
+		if err := job.StopSupervisor(st, 10*time.Second); err != nil {
+			return nil, fmt.Errorf("daemon: %w", err)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"os"
+
+	"github.com/haltman-io/gorunandcallme/internal/job"
+)
+
+// Jobs is the RPC service ("Jobs.<Method>"). Methods that mirror a job
+// subcommand return its printed output.
+type Jobs struct {
+	st         *job.Store
+	runnerPath string
+}
+
+type Empty struct{}
+
+type PingReply struct {
+	PID      int
+	StateDir string
+}
+
+type ListArgs struct {
+	Options job.ListOptions
+}
+
+type RefArgs struct {
+	Ref string
+}
+
+type StopArgs struct {
+	Ref     string
+	Options job.StopOptions
+}
+
+// StartArgs are the SpawnOptions of a new background job. Env is the
+// caller's environment, so the job runs as if started from its shell.
+type StartArgs struct {
+	RunnerArgs  []string
+	Workdir     string
+	Command     string
+	Tags        []string
+	Name        string
+	Labels      map[string]string
+	OutputFiles []string
+	Queue       string
+	Env         []string
+}
+
+type RerunArgs struct {
+	Ref       string
+	ExtraArgs []string
+	Stop      *job.StopOptions // restart
+	Env       []string
+}
+
+type Output struct {
+	Text string
+}
+
+type RerunReply struct {
+	Output
+	Result job.SpawnResult
+}
+
+func (j *Jobs) Ping(_ Empty, reply *PingReply) error {
+	*reply = PingReply{PID: os.Getpid(), StateDir: j.st.BaseDir}
+	return nil
+}
+
+func (j *Jobs) List(args ListArgs, reply *[]job.Meta) error {
+	jobs, err := job.ListJobs(j.st, args.Options)
+	if err != nil {
+		return err
+	}
+	*reply = jobs
+	return nil
+}
+
+func (j *Jobs) Status(args RefArgs, reply *Output) error {
+	var buf bytes.Buffer
+	err := job.CmdStatus(&buf, j.st, args.Ref)
+	reply.Text = buf.String()
+	return err
+}
+
+func (j *Jobs) Start(args StartArgs, reply *job.SpawnResult) error {
+	res, err := job.SpawnBackground(context.Background(), job.SpawnOptions{
+		RunnerPath:  j.runnerPath,
+		RunnerArgs:  args.RunnerArgs,
+		Workdir:     args.Workdir,
+		Store:       j.st,
+		Command:     args.Command,
+		Tags:        args.Tags,
+		Name:        args.Name,
+		Labels:      args.Labels,
+		OutputFiles: args.OutputFiles,
+		Queue:       args.Queue,
+		Env:         args.Env,
+	})
+	if err != nil {
+		return err
+	}
+	*reply = res
+	return nil
+}
+
+func (j *Jobs) Stop(args StopArgs, reply *Output) error {
+	var buf bytes.Buffer
+	err := job.CmdStop(&buf, j.st, args.Ref, args.Options)
+	reply.Text = buf.String()
+	return err
+}
+
+func (j *Jobs) Cancel(args RefArgs, reply *Output) error {
+	var buf bytes.Buffer
+	err := job.CmdCancel(&buf, j.st, args.Ref)
+	reply.Text = buf.String()
+	return err
+}
+
+func (j *Jobs) Purge(args RefArgs, reply *Output) error {
+	var buf bytes.Buffer
+	err := job.CmdPurge(&buf, j.st, args.Ref)
+	reply.Text = buf.String()
+	return err
+}
+
+func (j *Jobs) Rerun(args RerunArgs, reply *RerunReply) error {
+	var buf bytes.Buffer
+	res, err := job.CmdRerun(context.Background(), &buf, j.st, args.Ref, job.RerunOptions{
+		RunnerPath: j.runnerPath,
+		ExtraArgs:  args.ExtraArgs,
+		Stop:       args.Stop,
+		Env:        args.Env,
+	})
+	reply.Text = buf.String()
+	reply.Result = res
+	return err
+}
@@ -17,7 +17,7 @@ type Writer struct {
 type Event struct {
 	Time     string            `json:"time"`
 	Type     string            `json:"type"`   // line|lifecycle|notify|job
-	Stream   string            `json:"stream"` // stdout|stderr
+	Stream   string            `json:"stream"` // stdout|stderr, empty when unknown
 	JobID    string            `json:"job_id,omitempty"`
 	Command  string            `json:"command,omitempty"`
 	Message  string            `json:"message,omitempty"`
@@ -0,0 +1,29 @@
+//go:build windows
+
+package execx
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup is a no-op: Windows has no POSIX process groups.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// signalGroup signals p alone. Only SIGKILL is supported on Windows.
+func signalGroup(p *os.Process, sig syscall.Signal) error {
+	if sig == syscall.SIGKILL {
+		return p.Kill()
+	}
+	return p.Signal(sig)
+}
+
+// KillProcessGroup kills a child started by RunCommand.
+func KillProcessGroup(pid int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return nil
+	}
+	return p.Kill()
+}
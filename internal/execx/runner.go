@@ -52,32 +52,62 @@ type RunnerOptions struct {
 
 	// Scanner max token size for very long lines (defaults to 8 MiB).
 	MaxLineBytes int
+
+	// OnStart is called with the child PID right after it starts.
+	OnStart func(pid int)
+
+	// KillGrace is how long a child gets after SIGTERM when ctx ends before
+	// it is killed (defaults to 10s).
+	KillGrace time.Duration
+
+	// DrainTimeout is how long output is still read after the child exits,
+	// while descendants keep its stdout/stderr open (defaults to 2s).
+	DrainTimeout time.Duration
 }
 
-// LineHandler receives sanitized lines in real-time.
-type LineHandler func(line string)
+// LineHandler receives sanitized lines in real-time, with the stream
+// ("stdout" or "stderr") they came from.
+type LineHandler func(stream string, line string)
 
-// RunCommand executes cmd and streams stdout/stderr as lines.
+// RunCommand executes cmd and streams stdout/stderr as lines. When ctx ends
+// first, the child gets SIGTERM and, after KillGrace, SIGKILL.
 // It returns the process exit code (even when non-zero) and a hard error for start/wait issues.
 func RunCommand(ctx context.Context, cmd *exec.Cmd, opt RunnerOptions, onLine LineHandler) (int, error) {
 	if cmd == nil {
 		return 0, errors.New("nil cmd")
 	}
 	if onLine == nil {
-		onLine = func(string) {}
+		onLine = func(string, string) {}
 	}
 	if opt.MaxLineBytes <= 0 {
 		opt.MaxLineBytes = 8 * 1024 * 1024
 	}
+	if opt.KillGrace <= 0 {
+		opt.KillGrace = 10 * time.Second
+	}
+	if opt.DrainTimeout <= 0 {
+		opt.DrainTimeout = 2 * time.Second
+	}
 
-	stdout, err := cmd.StdoutPipe()
+	if cmd.Stdout != nil || cmd.Stderr != nil {
+		return 0, errors.New("cmd stdout/stderr already set")
+	}
+
+	// Own the pipes instead of using StdoutPipe/StderrPipe: Wait then returns
+	// as soon as the child exits, and the read ends can be closed after a
+	// bounded drain even if a descendant still holds the write ends.
+	stdout, stdoutW, err := os.Pipe()
 	if err != nil {
 		return 0, fmt.Errorf("stdout pipe: %w", err)
 	}
-	stderr, err := cmd.StderrPipe()
+	defer stdout.Close()
+	stderr, stderrW, err := os.Pipe()
 	if err != nil {
+		_ = stdoutW.Close()
 		return 0, fmt.Errorf("stderr pipe: %w", err)
 	}
+	defer stderr.Close()
+	cmd.Stdout, cmd.Stderr = stdoutW, stderrW
 
 	// If mirroring to tty is desired, tee both streams to stdout/stderr.
 	// But we still need line-based processing, so we do it by writing in the read loop.
@@ -88,14 +118,31 @@ func RunCommand(ctx context.Context, cmd *exec.Cmd, opt RunnerOptions, onLine Li
 		ttyErr = os.Stderr
 	}
 
-	if err := cmd.Start(); err != nil {
+	setProcessGroup(cmd)
+	err = cmd.Start()
+	_ = stdoutW.Close()
+	_ = stderrW.Close()
+	if err != nil {
 		return 0, fmt.Errorf("start: %w", err)
 	}
+	if opt.OnStart != nil {
+		opt.OnStart(cmd.Process.Pid)
+	}
+
+	exited := make(chan struct{})
+	defer close(exited)
+	go func() {
+		select {
+		case <-exited:
+		case <-ctx.Done():
+			terminate(cmd.Process, opt.KillGrace, exited, stdout, stderr)
+		}
+	}()
 
 	var wg sync.WaitGroup
 	wg.Add(2)
 
-	readStream := func(r io.Reader, mirror io.Writer) {
+	readStream := func(stream string, r io.Reader, mirror io.Writer) {
 		defer wg.Done()
 
 		sc := bufio.NewScanner(r)
@@ -126,15 +173,33 @@ func RunCommand(ctx context.Context, cmd *exec.Cmd, opt RunnerOptions, onLine Li
 				continue
 			}
 
-			onLine(line)
+			onLine(stream, line)
 		}
 	}
 
-	go readStream(stdout, ttyOut)
-	go readStream(stderr, ttyErr)
+	go readStream("stdout", stdout, ttyOut)
+	go readStream("stderr", stderr, ttyErr)
 
 	waitErr := cmd.Wait()
-	wg.Wait()
+
+	// Drain what the child wrote before exiting. Descendants that inherited
+	// the pipes (e.g. a backgrounded "sleep") keep them open, so stop reading
+	// after DrainTimeout instead of hanging until they exit.
+	drained := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(opt.DrainTimeout):
+		if opt.UI != nil {
+			opt.UI.Verbosef("output still open %s after exit, closing it", opt.DrainTimeout)
+		}
+		_ = stdout.Close()
+		_ = stderr.Close()
+		<-drained
+	}
 
 	exitCode := exitCodeFromWait(waitErr)
 
@@ -147,6 +212,23 @@ func RunCommand(ctx context.Context, cmd *exec.Cmd, opt RunnerOptions, onLine Li
 	return exitCode, nil
 }
 
+// terminate asks p's process group to exit and kills it if p is still
+// running after grace. The pipes are closed on kill so that descendants that
+// left the group (e.g. through setsid) cannot keep the readers blocked.
+func terminate(p *os.Process, grace time.Duration, exited <-chan struct{}, pipes ...io.Closer) {
+	if err := signalGroup(p, syscall.SIGTERM); err == nil { // no SIGTERM on Windows
+		select {
+		case <-exited:
+			return
+		case <-time.After(grace):
+		}
+	}
+	_ = signalGroup(p, syscall.SIGKILL)
+	for _, c := range pipes {
+		_ = c.Close()
+	}
+}
+
 func isExitStatus(err error) bool {
 	var ee *exec.ExitError
 	if errors.As(err, &ee) {
@@ -163,6 +245,10 @@ func exitCodeFromWait(err error) int {
 	if errors.As(err, &ee) {
 		// Unix
 		if ws, ok := ee.Sys().(syscall.WaitStatus); ok {
+			// Killed by a signal: use the shell convention 128+n.
+			if ws.Signaled() {
+				return 128 + int(ws.Signal())
+			}
 			return ws.ExitStatus()
 		}
 		// Fallback
@@ -33,6 +33,14 @@ type SchedulerOptions struct {
 	NotifyHook LineHook
 	OutputFile string
 	OutputMode string
+	OnStart    func(pid int)
+
+	// LineFields, if set, returns extra Fields of "line" events, e.g. the
+	// alert rule a line matches.
+	LineFields func(line string) map[string]string
+
+	// Context stops the command when done (default: never).
+	Context context.Context
 }
 
 type Scheduler struct {
@@ -65,26 +73,35 @@ func (s *Scheduler) Run(plan *Plan) (int, error) {
 		defer outFile.Close()
 	}
 
-	onLine := func(line string) {
+	onLine := func(stream string, line string) {
 		if s.opt.NotifyHook != nil {
-			s.opt.NotifyHook.OnLine("stdout", line)
+			s.opt.NotifyHook.OnLine(stream, line)
 		}
 		if outFile != nil {
 			_, _ = outFile.WriteString(line + "\n")
 		}
 		if s.opt.EventSink != nil {
-			_ = s.opt.EventSink.Write(event.Event{
+			ev := event.Event{
 				Type:    "line",
-				Stream:  "stdout",
+				Stream:  stream,
 				Message: line,
-			})
+			}
+			if s.opt.LineFields != nil {
+				ev.Fields = s.opt.LineFields(line)
+			}
+			_ = s.opt.EventSink.Write(ev)
 		}
 	}
 
-	exitCode, err := RunCommand(context.Background(), cmd, RunnerOptions{
+	ctx := s.opt.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	exitCode, err := RunCommand(ctx, cmd, RunnerOptions{
 		MirrorToTTY:   !s.opt.NoTTY,
 		StripANSI:     s.opt.StripANSI,
 		StripProgress: s.opt.StripProg,
+		OnStart:       s.opt.OnStart,
 	}, onLine)
 	if err != nil {
 		return exitCode, err
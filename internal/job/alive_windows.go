@@ -0,0 +1,26 @@
+//go:build windows
+
+package job
+
+import "syscall"
+
+const processQueryLimitedInformation = 0x1000
+
+// processAlive reports whether pid exists and has not exited.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(h)
+
+	var code uint32
+	if err := syscall.GetExitCodeProcess(h, &code); err != nil {
+		return false
+	}
+	const stillActive = 259
+	return code == stillActive
+}
@@ -0,0 +1,349 @@
+package job
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ManifestFileName is the last entry of a job bundle; it lists every other
+// entry with its size and SHA-256.
+const ManifestFileName = "manifest.json"
+
+// bundleFormat is bumped on incompatible bundle layout changes.
+const bundleFormat = 1
+
+type Manifest struct {
+	Format     int            `json:"format"`
+	JobID      string         `json:"job_id"`
+	ExportedAt time.Time      `json:"exported_at"`
+	Files      []ManifestFile `json:"files"`
+}
+
+type ManifestFile struct {
+	Path   string `json:"path"` // relative to the job dir, slash-separated
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	Source string `json:"source,omitempty"` // original absolute path for output files
+}
+
+// CmdExport bundles a job (meta, log segments and output files) into a
+// tar.gz at outPath, with a manifest of checksums.
+func CmdExport(w io.Writer, st *Store, jobRef string, outPath string) error {
+	jobID, err := ResolveJobRef(st, jobRef)
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	m, err := st.Refresh(jobID)
+	if err != nil {
+		return err
+	}
+	if outPath == "" {
+		outPath = jobID + ".tar.gz"
+	}
+
+	type item struct{ src, rel string }
+	var items []item
+
+	// Job dir content: meta.json, output.log and rotated segments.
+	entries, err := os.ReadDir(st.JobDir(jobID))
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || name == MetaLockFileName || strings.HasSuffix(name, ".tmp") {
+			continue
+		}
+		items = append(items, item{src: filepath.Join(st.JobDir(jobID), name), rel: name})
+	}
+	// Output files go under files/, de-duplicating base names.
+	seen := map[string]bool{}
+	for _, f := range m.OutputFiles {
+		if _, err := os.Stat(f); err != nil {
+			fmt.Fprintf(w, "export: skipping %s: %v\n", f, err)
+			continue
+		}
+		base := filepath.Base(f)
+		rel := "files/" + base
+		for i := 2; seen[rel]; i++ {
+			rel = fmt.Sprintf("files/%d-%s", i, base)
+		}
+		seen[rel] = true
+		items = append(items, item{src: f, rel: rel})
+	}
+
+	out, err := os.OpenFile(outPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	ok := false
+	defer func() {
+		_ = out.Close()
+		if !ok {
+			_ = os.Remove(outPath)
+		}
+	}()
+
+	zw := gzip.NewWriter(out)
+	tw := tar.NewWriter(zw)
+
+	man := Manifest{Format: bundleFormat, JobID: jobID, ExportedAt: time.Now().UTC()}
+	for _, it := range items {
+		mf, err := addTarFile(tw, path.Join(jobID, it.rel), it.src)
+		if err != nil {
+			return fmt.Errorf("export: %s: %w", it.src, err)
+		}
+		mf.Path = it.rel
+		if strings.HasPrefix(it.rel, "files/") {
+			mf.Source = it.src
+		}
+		man.Files = append(man.Files, mf)
+	}
+
+	b, err := json.MarshalIndent(man, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    path.Join(jobID, ManifestFileName),
+		Mode:    0o600,
+		Size:    int64(len(b)),
+		ModTime: man.ExportedAt,
+	}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(b); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	ok = true
+
+	if m.Active() {
+		fmt.Fprintf(w, "export: job %s is still running; the bundle is a snapshot\n", jobID)
+	}
+	fmt.Fprintf(w, "exported: %s -> %s (%d files)\n", jobID, outPath, len(man.Files))
+	return nil
+}
+
+// addTarFile writes src as name and returns its size and checksum. Files that
+// grow while being read (running jobs) are cut at the size seen at stat time.
+func addTarFile(tw *tar.Writer, name string, src string) (ManifestFile, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return ManifestFile{}, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return ManifestFile{}, err
+	}
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o600,
+		Size:    fi.Size(),
+		ModTime: fi.ModTime(),
+	}); err != nil {
+		return ManifestFile{}, err
+	}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tw, h), io.LimitReader(f, fi.Size()))
+	if err != nil {
+		return ManifestFile{}, err
+	}
+	if n != fi.Size() {
+		return ManifestFile{}, fmt.Errorf("file shrank while reading (%d of %d bytes)", n, fi.Size())
+	}
+	return ManifestFile{Size: n, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// CmdImport restores a bundle created by CmdExport into st, keeping the job
+// ID. It refuses to overwrite an existing job and verifies every checksum
+// before the job becomes visible.
+func CmdImport(w io.Writer, st *Store, bundlePath string) error {
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+	tr := tar.NewReader(zr)
+
+	tmp, err := os.MkdirTemp(st.JobsDir(), ".import-")
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	var jobID string
+	var man *Manifest
+	sums := map[string]string{}
+	sizes := map[string]int64{}
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("import: %w", err)
+		}
+		// Bundles repacked with other tools may carry directory entries.
+		if hdr.Typeflag == tar.TypeDir {
+			continue
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return fmt.Errorf("import: unexpected entry %q", hdr.Name)
+		}
+		id, rel, err := splitBundlePath(hdr.Name)
+		if err != nil {
+			return fmt.Errorf("import: %w", err)
+		}
+		if jobID == "" {
+			jobID = id
+		} else if id != jobID {
+			return fmt.Errorf("import: bundle mixes jobs %s and %s", jobID, id)
+		}
+
+		if rel == ManifestFileName {
+			man = &Manifest{}
+			if err := json.NewDecoder(tr).Decode(man); err != nil {
+				return fmt.Errorf("import: manifest: %w", err)
+			}
+			continue
+		}
+
+		dst := filepath.Join(tmp, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(dst), 0o700); err != nil {
+			return fmt.Errorf("import: %w", err)
+		}
+		out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err != nil {
+			return fmt.Errorf("import: %w", err)
+		}
+		h := sha256.New()
+		n, err := io.Copy(io.MultiWriter(out, h), tr)
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("import: %s: %w", rel, err)
+		}
+		sums[rel] = hex.EncodeToString(h.Sum(nil))
+		sizes[rel] = n
+	}
+
+	if man == nil {
+		return errors.New("import: bundle has no manifest")
+	}
+	if man.Format != bundleFormat {
+		return fmt.Errorf("import: unsupported bundle format %d", man.Format)
+	}
+	if man.JobID != jobID {
+		return fmt.Errorf("import: manifest is for job %s, bundle contains %s", man.JobID, jobID)
+	}
+	for _, mf := range man.Files {
+		got, ok := sums[mf.Path]
+		if !ok {
+			return fmt.Errorf("import: %s listed in manifest but missing", mf.Path)
+		}
+		if got != mf.SHA256 || sizes[mf.Path] != mf.Size {
+			return fmt.Errorf("import: checksum mismatch for %s", mf.Path)
+		}
+		delete(sums, mf.Path)
+	}
+	for rel := range sums {
+		return fmt.Errorf("import: %s is not listed in the manifest", rel)
+	}
+
+	// Point the meta at its new home.
+	metaPath := filepath.Join(tmp, MetaFileName)
+	b, err := os.ReadFile(metaPath)
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+	var m Meta
+	if err := json.Unmarshal(b, &m); err != nil {
+		return fmt.Errorf("import: meta: %w", err)
+	}
+	if m.ID != jobID {
+		return fmt.Errorf("import: meta is for job %s, bundle contains %s", m.ID, jobID)
+	}
+	m.LogPath = st.LogPath(jobID)
+	m.OutputFiles = nil
+	for _, mf := range man.Files {
+		if strings.HasPrefix(mf.Path, "files/") {
+			m.OutputFiles = append(m.OutputFiles, filepath.Join(st.JobDir(jobID), filepath.FromSlash(mf.Path)))
+		}
+	}
+	if !m.Ended() {
+		// Its runner lives on another machine (or is long gone).
+		now := time.Now().UTC()
+		m.ErrorText = "imported while " + string(m.Status)
+		m.Status = StatusLost
+		m.EndedAt = &now
+	}
+	m.PIDStart, m.ChildPIDStart = "", ""
+	b, err = json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(metaPath, b, 0o600); err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+
+	// Rename fails if the target exists, so a concurrent import cannot clobber it.
+	if _, err := os.Stat(st.JobDir(jobID)); err == nil {
+		return fmt.Errorf("import: job %s already exists in %s", jobID, st.JobsDir())
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("import: %w", err)
+	}
+	if err := os.Rename(tmp, st.JobDir(jobID)); err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+	fmt.Fprintf(w, "imported: %s (%d files, status=%s)\n", jobID, len(man.Files), m.Status)
+	return nil
+}
+
+// splitBundlePath validates "<job-id>/<rel>" entry names and rejects
+// anything that could escape the job dir. Backslashes and colons are
+// rejected too: they are separators (or volume names) on Windows.
+func splitBundlePath(name string) (string, string, error) {
+	clean := path.Clean(name)
+	if clean != name || path.IsAbs(clean) || strings.HasPrefix(clean, "../") || strings.ContainsAny(clean, `\:`) {
+		return "", "", fmt.Errorf("unsafe entry %q", name)
+	}
+	id, rel, ok := strings.Cut(clean, "/")
+	if !ok || id == "" || id == "." || id == ".." || strings.HasPrefix(id, ".") || rel == "" {
+		return "", "", fmt.Errorf("unexpected entry %q", name)
+	}
+	for _, part := range strings.Split(rel, "/") {
+		if part == ".." || part == "." || part == "" {
+			return "", "", fmt.Errorf("unsafe entry %q", name)
+		}
+	}
+	return id, rel, nil
+}
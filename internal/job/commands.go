@@ -9,57 +9,72 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 )
 
-type ListOptions struct {
-	Limit int // 0 = no limit
-}
-
-func CmdList(w io.Writer, st *Store, opt ListOptions) error {
-	ids, err := st.ListJobIDs()
+func CmdStatus(w io.Writer, st *Store, jobID string) error {
+	jobID, err := ResolveJobRef(st, jobID)
+	if err != nil {
+		return fmt.Errorf("status: %w", err)
+	}
+	m, err := st.Refresh(jobID)
 	if err != nil {
 		return err
 	}
-	// Show newest first (IDs start with timestamp)
-	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
 
-	if opt.Limit > 0 && len(ids) > opt.Limit {
-		ids = ids[:opt.Limit]
+	fmt.Fprintf(w, "id: %s\n", m.ID)
+	if m.Name != "" {
+		fmt.Fprintf(w, "name: %s\n", m.Name)
 	}
-
-	for _, id := range ids {
-		m, err := st.ReadMeta(id)
-		if err != nil {
-			fmt.Fprintf(w, "%s  (meta error: %v)\n", id, err)
-			continue
+	if len(m.Labels) > 0 {
+		keys := make([]string, 0, len(m.Labels))
+		for k := range m.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(w, "label: %s=%s\n", k, m.Labels[k])
 		}
-		fmt.Fprintf(w, "%s  pid=%d  status=%s  started=%s\n",
-			m.ID, m.PID, m.Status, m.StartedAt.Format(time.RFC3339))
 	}
-	return nil
-}
-
-func CmdStatus(w io.Writer, st *Store, jobID string) error {
-	jobID = strings.TrimSpace(jobID)
-	if jobID == "" {
-		return errors.New("status: empty job id")
+	if m.ParentID != "" {
+		fmt.Fprintf(w, "parent: %s\n", m.ParentID)
 	}
-	m, err := st.ReadMeta(jobID)
-	if err != nil {
-		return err
+	if m.Command != "" {
+		fmt.Fprintf(w, "command: %s\n", m.Command)
+	}
+	if len(m.Tags) > 0 {
+		fmt.Fprintf(w, "tags: %s\n", strings.Join(m.Tags, ","))
 	}
-
-	fmt.Fprintf(w, "id: %s\n", m.ID)
 	fmt.Fprintf(w, "pid: %d\n", m.PID)
+	if m.ChildPID > 0 {
+		fmt.Fprintf(w, "child_pid: %d\n", m.ChildPID)
+	}
 	fmt.Fprintf(w, "status: %s\n", m.Status)
+	if m.Queue != "" {
+		fmt.Fprintf(w, "queue: %s\n", m.Queue)
+	}
+	if m.Status == StatusQueued {
+		if pos, err := QueuePositions(st); err == nil && pos[m.ID] > 0 {
+			fmt.Fprintf(w, "queue_position: %d\n", pos[m.ID])
+		}
+		if m.QueuedAt != nil {
+			fmt.Fprintf(w, "queued_at: %s\n", m.QueuedAt.Format(time.RFC3339))
+		}
+	}
 	fmt.Fprintf(w, "started_at: %s\n", m.StartedAt.Format(time.RFC3339))
 	if m.EndedAt != nil {
 		fmt.Fprintf(w, "ended_at: %s\n", m.EndedAt.Format(time.RFC3339))
 	}
+	if d := m.Duration(); d > 0 {
+		fmt.Fprintf(w, "duration: %s\n", formatDuration(d))
+	}
 	if m.ExitCode != nil {
 		fmt.Fprintf(w, "exit_code: %d\n", *m.ExitCode)
 	}
+	if m.Signal != "" {
+		fmt.Fprintf(w, "signal: %s\n", m.Signal)
+	}
 	if m.ErrorText != "" {
 		fmt.Fprintf(w, "error: %s\n", m.ErrorText)
 	}
@@ -91,9 +106,9 @@ func CmdFollow(jobID string, opt FollowOptions) error {
 		opt.Ctx = context.Background()
 	}
 
-	jobID = strings.TrimSpace(jobID)
-	if jobID == "" {
-		return errors.New("follow: empty job id")
+	jobID, err := ResolveJobRef(opt.JobStore, jobID)
+	if err != nil {
+		return fmt.Errorf("follow: %w", err)
 	}
 
 	m, err := opt.JobStore.ReadMeta(jobID)
@@ -118,39 +133,140 @@ func CmdFollow(jobID string, opt FollowOptions) error {
 	return FollowFile(opt.Ctx, opt.Stdout, m.LogPath, opt.Poll)
 }
 
-func CmdStop(w io.Writer, st *Store, jobID string) error {
-	jobID = strings.TrimSpace(jobID)
-	if jobID == "" {
-		return errors.New("stop: empty job id")
+// StopOptions controls how CmdStop terminates a job.
+type StopOptions struct {
+	Signal string        // signal name or number; default SIGTERM
+	Grace  time.Duration // wait this long before SIGKILL; default 10s
+	Poll   time.Duration // liveness polling interval; default 200ms
+}
+
+// CmdStop signals the job's process group, waits up to Grace for the runner
+// to record its own exit and escalates to SIGKILL if it does not.
+func CmdStop(w io.Writer, st *Store, jobID string, opt StopOptions) error {
+	jobID, err := ResolveJobRef(st, jobID)
+	if err != nil {
+		return fmt.Errorf("stop: %w", err)
+	}
+	sig, err := ParseSignal(opt.Signal)
+	if err != nil {
+		return fmt.Errorf("stop: %w", err)
+	}
+	if opt.Grace <= 0 {
+		opt.Grace = 10 * time.Second
 	}
-	m, err := st.ReadMeta(jobID)
+	if opt.Poll <= 0 {
+		opt.Poll = 200 * time.Millisecond
+	}
+
+	m, err := st.Refresh(jobID)
 	if err != nil {
 		return err
 	}
+	if m.Status == StatusQueued {
+		return fmt.Errorf("stop: job %s is still queued (use job cancel)", jobID)
+	}
+	if !m.Active() {
+		return fmt.Errorf("stop: job %s is not running (status=%s)", jobID, m.Status)
+	}
 	if m.PID <= 0 {
 		return fmt.Errorf("stop: job %s has invalid pid", jobID)
 	}
+	// Never signal a PID that now belongs to a different process.
+	if !m.RunnerAlive() {
+		return fmt.Errorf("stop: pid %d no longer belongs to job %s; refusing to signal", m.PID, jobID)
+	}
 
-	if err := KillPID(m.PID); err != nil {
-		return fmt.Errorf("stop: kill pid %d: %w", m.PID, err)
+	// Record the signal first, so the worker knows the whole group got it.
+	prevSignal := m.Signal
+	_, _ = st.UpdateMeta(jobID, func(m *Meta) error {
+		m.Signal = signalName(sig)
+		return nil
+	})
+	if err := signalJob(m, sig); err != nil {
+		_, _ = st.UpdateMeta(jobID, func(m *Meta) error {
+			m.Signal = prevSignal
+			return nil
+		})
+		return fmt.Errorf("stop: signal pid %d: %w", m.PID, err)
 	}
 
-	now := time.Now().UTC()
-	m.Status = StatusStopped
-	m.EndedAt = &now
-	code := 0
-	m.ExitCode = &code
+	final := sig
+	if sig != syscall.SIGKILL && !waitRunnerExit(m, opt.Grace, opt.Poll) {
+		fmt.Fprintf(w, "job %s did not exit after %s; sending SIGKILL\n", jobID, opt.Grace)
+		final = syscall.SIGKILL
+		if err := signalJob(m, syscall.SIGKILL); err != nil {
+			return fmt.Errorf("stop: kill pid %d: %w", m.PID, err)
+		}
+		_ = waitRunnerExit(m, 5*time.Second, opt.Poll)
+	}
 
-	_ = st.WriteMeta(m)
+	// The worker normally records the real exit status itself; fill it in
+	// only if it was killed before it could.
+	m, err = st.UpdateMeta(jobID, func(m *Meta) error {
+		if !m.Active() {
+			return nil
+		}
+		now := time.Now().UTC()
+		code := signalExitCode(final)
+		m.Status = StatusStopped
+		m.Signal = signalName(final)
+		m.ExitCode = &code
+		m.EndedAt = &now
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	exit := "?"
+	if m.ExitCode != nil {
+		exit = strconv.Itoa(*m.ExitCode)
+	}
+	fmt.Fprintf(w, "stopped: %s (pid=%d, signal=%s, exit=%s)\n", m.ID, m.PID, m.Signal, exit)
+	return nil
+}
+
+// waitRunnerExit polls until the runner is gone or timeout passes.
+func waitRunnerExit(m Meta, timeout, poll time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if !m.RunnerAlive() {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(poll)
+	}
+}
 
-	fmt.Fprintf(w, "stopped: %s (pid=%d)\n", m.ID, m.PID)
+// CmdCancel removes a queued job from its queue before it starts. The job is
+// kept as "canceled" so it still shows up in job list until pruned.
+func CmdCancel(w io.Writer, st *Store, jobID string) error {
+	jobID, err := ResolveJobRef(st, jobID)
+	if err != nil {
+		return fmt.Errorf("cancel: %w", err)
+	}
+	m, err := st.UpdateMeta(jobID, func(m *Meta) error {
+		if m.Status != StatusQueued {
+			return fmt.Errorf("cancel: job %s is not queued (status=%s)", m.ID, m.Status)
+		}
+		now := time.Now().UTC()
+		m.Status = StatusCanceled
+		m.EndedAt = &now
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "canceled: %s (queue=%s)\n", m.ID, m.Queue)
 	return nil
 }
 
 func CmdPurge(w io.Writer, st *Store, jobID string) error {
-	jobID = strings.TrimSpace(jobID)
-	if jobID == "" {
-		return errors.New("purge: empty job id")
+	jobID, err := ResolveJobRef(st, jobID)
+	if err != nil {
+		return fmt.Errorf("purge: %w", err)
 	}
 	if err := st.DeleteJob(jobID); err != nil {
 		return err
@@ -159,7 +275,8 @@ func CmdPurge(w io.Writer, st *Store, jobID string) error {
 	return nil
 }
 
-// Optional helper: accept numeric index from "job list" output (if you decide to display indices).
+// ParseJobIDOrIndex accepts a job ID or a numeric index into the newest-first
+// "job list" output.
 func ParseJobIDOrIndex(st *Store, idOrIndex string) (string, error) {
 	idOrIndex = strings.TrimSpace(idOrIndex)
 	if idOrIndex == "" {
@@ -184,3 +301,59 @@ func ParseJobIDOrIndex(st *Store, idOrIndex string) (string, error) {
 
 	return idOrIndex, nil
 }
+
+// ResolveJobRef accepts a job ID, a list index or a job name (--job-name).
+// Names may repeat; the newest job with that name wins.
+func ResolveJobRef(st *Store, ref string) (string, error) {
+	id, err := ParseJobIDOrIndex(st, ref)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(st.MetaPath(id)); err == nil {
+		return id, nil
+	}
+
+	ids, err := st.ListJobIDs()
+	if err != nil {
+		return "", err
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+	for _, cand := range ids {
+		m, err := st.ReadMeta(cand)
+		if err == nil && m.Name == id {
+			return cand, nil
+		}
+	}
+	return "", fmt.Errorf("no job with id or name %q", id)
+}
+
+// ValidateJobName rejects names that could be confused with an ID or index.
+func ValidateJobName(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, err := strconv.Atoi(name); err == nil {
+		return fmt.Errorf("job name %q must not be a number", name)
+	}
+	if strings.ContainsAny(name, " \t\r\n/\\") {
+		return fmt.Errorf("job name %q must not contain spaces or slashes", name)
+	}
+	return nil
+}
+
+// ParseLabels parses repeatable key=value pairs.
+func ParseLabels(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	out := map[string]string{}
+	for _, p := range pairs {
+		k, v, ok := strings.Cut(p, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid label %q (want key=value)", p)
+		}
+		out[k] = strings.TrimSpace(v)
+	}
+	return out, nil
+}
@@ -10,13 +10,13 @@ import (
 // daemonize configures the exec.Cmd to detach from the current session/terminal.
 // On Unix-like systems, Setsid makes the process a new session leader, effectively
 // detaching it from the controlling terminal.
+//
+// A session leader also leads a new process group (pgid == pid), so the runner
+// and the tool it starts can be signaled together. Setpgid must not be combined
+// with Setsid: setpgid() on a session leader fails with EPERM and the spawn fails.
 func daemonize(cmd *exec.Cmd) {
 	if cmd.SysProcAttr == nil {
 		cmd.SysProcAttr = &syscall.SysProcAttr{}
 	}
 	cmd.SysProcAttr.Setsid = true
-
-	// Optional: put the process in its own process group.
-	// This helps when you want to signal the whole group later.
-	cmd.SysProcAttr.Setpgid = true
 }
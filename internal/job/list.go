@@ -0,0 +1,235 @@
+package job
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"github.com/haltman-io/gorunandcallme/internal/util"
+)
+
+type ListOptions struct {
+	Limit int // 0 = no limit
+
+	// Filters
+	Statuses []Status      // empty = any
+	Since    time.Duration // only jobs started within this window; 0 = any
+	Grep     string        // case-insensitive substring of the command
+	Labels   []string      // "key=value" or "key" selectors; all must match
+
+	// Output
+	JSON   bool   // JSON array of job metas
+	Format string // text/template executed per job, e.g. "{{.ID}} {{.Status}}"
+	Color  bool   // color the status column in the table
+}
+
+// ParseStatuses parses a comma-separated status list ("running,failed").
+func ParseStatuses(values []string) ([]Status, error) {
+	var out []Status
+	for _, v := range util.NormalizeCSV(values) {
+		st := Status(strings.ToLower(v))
+		switch st {
+		case StatusQueued, StatusStarting, StatusRunning, StatusFinished, StatusFailed, StatusStopped,
+			StatusLost, StatusCanceled:
+			out = append(out, st)
+		default:
+			return nil, fmt.Errorf("unknown status %q", v)
+		}
+	}
+	return out, nil
+}
+
+// ListJobs returns job metas newest first, refreshed for liveness and filtered by opt.
+func ListJobs(st *Store, opt ListOptions) ([]Meta, error) {
+	ids, err := st.ListJobIDs()
+	if err != nil {
+		return nil, err
+	}
+	// Show newest first (IDs start with timestamp)
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+
+	grep := strings.ToLower(strings.TrimSpace(opt.Grep))
+	var out []Meta
+	for _, id := range ids {
+		m, err := st.Refresh(id)
+		if err != nil {
+			// Keep unreadable jobs visible instead of silently hiding them.
+			m = Meta{ID: id, ErrorText: err.Error()}
+		}
+		if len(opt.Statuses) > 0 && !hasStatus(opt.Statuses, m.Status) {
+			continue
+		}
+		if opt.Since > 0 && time.Since(m.StartedAt) > opt.Since {
+			continue
+		}
+		if grep != "" && !strings.Contains(strings.ToLower(m.CommandLine()), grep) {
+			continue
+		}
+		if !matchLabels(m.Labels, opt.Labels) {
+			continue
+		}
+		out = append(out, m)
+		if opt.Limit > 0 && len(out) >= opt.Limit {
+			break
+		}
+	}
+
+	var pos map[string]int
+	for i := range out {
+		if out[i].Status != StatusQueued {
+			continue
+		}
+		if pos == nil {
+			if pos, err = QueuePositions(st); err != nil {
+				return nil, err
+			}
+		}
+		out[i].QueuePosition = pos[out[i].ID]
+	}
+	return out, nil
+}
+
+func CmdList(w io.Writer, st *Store, opt ListOptions) error {
+	jobs, err := ListJobs(st, opt)
+	if err != nil {
+		return err
+	}
+	return RenderList(w, jobs, opt)
+}
+
+// RenderList writes jobs as JSON, a per-job template or a table.
+func RenderList(w io.Writer, jobs []Meta, opt ListOptions) error {
+	switch {
+	case opt.JSON:
+		if jobs == nil {
+			jobs = []Meta{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(jobs)
+
+	case opt.Format != "":
+		tmpl, err := template.New("list").Funcs(template.FuncMap{
+			"join": strings.Join,
+		}).Parse(opt.Format)
+		if err != nil {
+			return fmt.Errorf("list: format: %w", err)
+		}
+		for _, m := range jobs {
+			if err := tmpl.Execute(w, m); err != nil {
+				return fmt.Errorf("list: format: %w", err)
+			}
+			fmt.Fprintln(w)
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tSTATUS\tPID\tDURATION\tEXIT\tTAGS\tCOMMAND")
+	for _, m := range jobs {
+		status := string(m.Status)
+		if m.ErrorText != "" && m.Status == "" {
+			status = "error"
+		}
+		if m.Status == StatusQueued && m.QueuePosition > 0 {
+			status = fmt.Sprintf("queued #%d (%s)", m.QueuePosition, m.Queue)
+		}
+		if opt.Color {
+			status = util.Colorize(statusColor(m.Status), status)
+		}
+		exit := "-"
+		if m.ExitCode != nil {
+			exit = strconv.Itoa(*m.ExitCode)
+		}
+		tags := "-"
+		if len(m.Tags) > 0 {
+			tags = strings.Join(m.Tags, ",")
+		}
+		name := m.Name
+		if name == "" {
+			name = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\n",
+			m.ID, name, status, m.PID, formatDuration(m.Duration()), exit, tags, truncateCommand(m.CommandLine(), 60))
+	}
+	return tw.Flush()
+}
+
+// Duration is the job's total runtime, or the elapsed time for active jobs.
+func (m Meta) Duration() time.Duration {
+	if m.StartedAt.IsZero() || m.Status == StatusQueued || m.Status == StatusCanceled {
+		return 0
+	}
+	if m.EndedAt != nil {
+		return m.EndedAt.Sub(m.StartedAt)
+	}
+	if m.Active() {
+		return time.Since(m.StartedAt)
+	}
+	return 0
+}
+
+// CommandLine is the target command, or the runner args for jobs created
+// before the command was recorded.
+func (m Meta) CommandLine() string {
+	if m.Command != "" {
+		return m.Command
+	}
+	return QuoteArgs(m.RunnerArgs)
+}
+
+func matchLabels(labels map[string]string, selectors []string) bool {
+	for _, sel := range selectors {
+		k, v, hasValue := strings.Cut(sel, "=")
+		got, ok := labels[strings.TrimSpace(k)]
+		if !ok || hasValue && got != strings.TrimSpace(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasStatus(list []Status, s Status) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func statusColor(s Status) string {
+	switch s {
+	case StatusRunning, StatusStarting:
+		return util.ColorCyan
+	case StatusFinished:
+		return util.ColorGreen
+	case StatusFailed, StatusLost:
+		return util.ColorRed
+	case StatusStopped, StatusCanceled:
+		return util.ColorYellow
+	default:
+		return util.ColorGray
+	}
+}
+
+func formatDuration(d time.Duration) string {
+	if d <= 0 {
+		return "-"
+	}
+	return d.Round(time.Second).String()
+}
+
+func truncateCommand(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max-3]) + "..."
+}
@@ -0,0 +1,73 @@
+package job
+
+import (
+	"fmt"
+	"time"
+)
+
+// startingGrace is how long a job may stay in "starting" without a PID
+// before it is considered lost.
+const startingGrace = time.Minute
+
+// Active reports whether the job is expected to have a live runner.
+func (m Meta) Active() bool {
+	return m.Status == StatusStarting || m.Status == StatusRunning
+}
+
+// Ended reports whether the job is done for good: neither running nor
+// waiting in a queue.
+func (m Meta) Ended() bool {
+	return !m.Active() && m.Status != StatusQueued
+}
+
+// processMatches reports whether pid is alive (not a zombie) and, when a
+// fingerprint was recorded, still the same process instance.
+func processMatches(pid int, start string) bool {
+	if !processAlive(pid) {
+		return false
+	}
+	cur, err := processStartTime(pid)
+	if err != nil {
+		return false
+	}
+	return start == "" || cur == "" || cur == start
+}
+
+// RunnerAlive reports whether the job's runner process is still running.
+func (m Meta) RunnerAlive() bool {
+	if m.PID <= 0 {
+		return false
+	}
+	return processMatches(m.PID, m.PIDStart)
+}
+
+// Refresh reads the job meta and marks active jobs whose runner is gone as
+// lost. Jobs that are not active are returned unchanged.
+func (s *Store) Refresh(id string) (Meta, error) {
+	m, err := s.ReadMeta(id)
+	if err != nil || !m.Active() || !isLost(m) {
+		return m, err
+	}
+	return s.UpdateMeta(id, func(m *Meta) error {
+		// Re-check under the lock: the worker may have just finished.
+		if !m.Active() || !isLost(*m) {
+			return nil
+		}
+		now := time.Now().UTC()
+		m.Status = StatusLost
+		m.EndedAt = &now
+		if m.PID > 0 {
+			m.ErrorText = fmt.Sprintf("runner process %d is gone", m.PID)
+		} else {
+			m.ErrorText = "runner never started"
+		}
+		return nil
+	})
+}
+
+func isLost(m Meta) bool {
+	if m.PID <= 0 {
+		return m.Status == StatusStarting && time.Since(m.StartedAt) > startingGrace
+	}
+	return !m.RunnerAlive()
+}
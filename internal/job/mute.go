@@ -0,0 +1,52 @@
+package job
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MuteFileName holds the time until which a job's alerts are muted.
+const MuteFileName = "alerts.mute"
+
+// muteRecheck is how often a running job re-reads MuteFileName.
+const muteRecheck = 5 * time.Second
+
+// MuteAlerts silences the job's alert notifications until the given time.
+// The runner re-reads it every few seconds, so it applies to running jobs.
+func (s *Store) MuteAlerts(id string, until time.Time) error {
+	if _, err := s.ReadMeta(id); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.JobDir(id), MuteFileName), []byte(until.UTC().Format(time.RFC3339)+"\n"), 0o600)
+}
+
+// AlertsMutedUntil returns when the job's alert mute ends (zero if not muted).
+func (s *Store) AlertsMutedUntil(id string) time.Time {
+	b, err := os.ReadFile(filepath.Join(s.JobDir(id), MuteFileName))
+	if err != nil {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(string(b)))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// AlertsMuted reports whether alerts of the worker's job are muted right now.
+// The mute file is read at most once per muteRecheck.
+func (w *Worker) AlertsMuted() bool {
+	if w == nil {
+		return false
+	}
+	now := time.Now()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if now.Sub(w.muteChecked) >= muteRecheck {
+		w.mutedUntil = w.st.AlertsMutedUntil(w.id)
+		w.muteChecked = now
+	}
+	return now.Before(w.mutedUntil)
+}
@@ -0,0 +1,37 @@
+//go:build linux
+
+package job
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// processStartTime returns the start time of pid in clock ticks since boot
+// (field 22 of /proc/<pid>/stat). Together with the PID it identifies a
+// process instance, so a recycled PID does not match a stored fingerprint.
+func processStartTime(pid int) (string, error) {
+	b, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return "", err
+	}
+	// The command name (field 2) is in parentheses and may contain spaces.
+	s := string(b)
+	i := strings.LastIndexByte(s, ')')
+	if i < 0 {
+		return "", errors.New("malformed /proc stat")
+	}
+	// Fields after ")" start at field 3 (state); starttime is field 22.
+	fields := strings.Fields(s[i+1:])
+	if len(fields) < 20 {
+		return "", fmt.Errorf("short /proc stat for pid %d", pid)
+	}
+	// An unreaped zombie still has a PID but is no longer running.
+	if fields[0] == "Z" || fields[0] == "X" {
+		return "", fmt.Errorf("pid %d has exited", pid)
+	}
+	return fields[19], nil
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package job
+
+// processStartTime is only available on Linux; elsewhere fingerprints are
+// empty and liveness falls back to the PID alone.
+func processStartTime(pid int) (string, error) {
+	return "", nil
+}
@@ -0,0 +1,148 @@
+package job
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/haltman-io/gorunandcallme/internal/util"
+)
+
+type PruneOptions struct {
+	OlderThan    time.Duration // prune jobs started longer ago than this
+	Statuses     []Status      // only prune these statuses (default: any ended job)
+	KeepLast     int           // always keep the newest N jobs
+	MaxTotalSize int64         // prune oldest jobs while the jobs dir is larger than this
+	DryRun       bool
+}
+
+// Empty reports whether no pruning criterion is set.
+func (o PruneOptions) Empty() bool {
+	return o.OlderThan <= 0 && len(o.Statuses) == 0 && o.KeepLast <= 0 && o.MaxTotalSize <= 0
+}
+
+// PruneResult describes one pruned (or, with DryRun, prunable) job.
+type PruneResult struct {
+	Meta   Meta
+	Size   int64
+	Reason string
+}
+
+// Prune deletes ended jobs matching opt. Active and queued jobs are never pruned.
+//
+// OlderThan, Statuses and KeepLast select jobs together (all set criteria
+// must match). MaxTotalSize then removes the oldest remaining jobs outside
+// KeepLast until the total size fits.
+func Prune(st *Store, opt PruneOptions) ([]PruneResult, error) {
+	if opt.Empty() {
+		return nil, errors.New("prune: no criteria given")
+	}
+
+	ids, err := st.ListJobIDs()
+	if err != nil {
+		return nil, err
+	}
+	// Newest first, so KeepLast protects the head of the list.
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+
+	type entry struct {
+		meta Meta
+		size int64
+		keep bool
+	}
+	entries := make([]entry, 0, len(ids))
+	var total int64
+	for i, id := range ids {
+		m, err := st.Refresh(id)
+		if err != nil {
+			// Broken job dirs are still counted, never silently deleted.
+			m = Meta{ID: id, Status: StatusRunning}
+		}
+		size := dirSize(st.JobDir(id))
+		total += size
+		entries = append(entries, entry{
+			meta: m,
+			size: size,
+			keep: !m.Ended() || i < opt.KeepLast,
+		})
+	}
+
+	var out []PruneResult
+	remove := func(e *entry, reason string) error {
+		if !opt.DryRun {
+			if err := st.DeleteJob(e.meta.ID); err != nil {
+				return err
+			}
+		}
+		e.keep = true // never consider it again
+		total -= e.size
+		out = append(out, PruneResult{Meta: e.meta, Size: e.size, Reason: reason})
+		return nil
+	}
+
+	if opt.OlderThan > 0 || len(opt.Statuses) > 0 || opt.KeepLast > 0 {
+		for i := range entries {
+			e := &entries[i]
+			if e.keep {
+				continue
+			}
+			if opt.OlderThan > 0 && time.Since(e.meta.StartedAt) < opt.OlderThan {
+				continue
+			}
+			if len(opt.Statuses) > 0 && !hasStatus(opt.Statuses, e.meta.Status) {
+				continue
+			}
+			if err := remove(e, "policy"); err != nil {
+				return out, err
+			}
+		}
+	}
+
+	if opt.MaxTotalSize > 0 {
+		// Oldest first.
+		for i := len(entries) - 1; i >= 0 && total > opt.MaxTotalSize; i-- {
+			e := &entries[i]
+			if e.keep {
+				continue
+			}
+			if err := remove(e, "size"); err != nil {
+				return out, err
+			}
+		}
+	}
+
+	return out, nil
+}
+
+func CmdPrune(w io.Writer, st *Store, opt PruneOptions) error {
+	res, err := Prune(st, opt)
+	verb := "pruned"
+	if opt.DryRun {
+		verb = "would prune"
+	}
+	var freed int64
+	for _, r := range res {
+		freed += r.Size
+		fmt.Fprintf(w, "%s: %s  status=%s  size=%s  (%s)\n", verb, r.Meta.ID, r.Meta.Status, util.FormatByteSize(r.Size), r.Reason)
+	}
+	fmt.Fprintf(w, "%s %d job(s), %s\n", verb, len(res), util.FormatByteSize(freed))
+	return err
+}
+
+func dirSize(dir string) int64 {
+	var n int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if fi, err := d.Info(); err == nil {
+			n += fi.Size()
+		}
+		return nil
+	})
+	return n
+}
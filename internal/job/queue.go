@@ -0,0 +1,292 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	SupervisorPIDFileName = "supervisor.pid"
+	SupervisorLogFileName = "supervisor.log"
+)
+
+// QueuePositions maps every queued job ID to its 1-based position in its
+// queue. Jobs are started in the order they were queued (oldest ID first).
+func QueuePositions(st *Store) (map[string]int, error) {
+	queued, err := queuedJobs(st)
+	if err != nil {
+		return nil, err
+	}
+	pos := map[string]int{}
+	for _, jobs := range queued {
+		for i, m := range jobs {
+			pos[m.ID] = i + 1
+		}
+	}
+	return pos, nil
+}
+
+// queuedJobs returns queued jobs per queue, oldest first.
+func queuedJobs(st *Store) (map[string][]Meta, error) {
+	ids, err := st.ListJobIDs()
+	if err != nil {
+		return nil, err
+	}
+	out := map[string][]Meta{}
+	for _, id := range ids {
+		m, err := st.ReadMeta(id)
+		if err != nil || m.Status != StatusQueued {
+			continue
+		}
+		out[m.Queue] = append(out[m.Queue], m)
+	}
+	return out, nil
+}
+
+type SuperviseOptions struct {
+	// Limit returns the max concurrency of a queue.
+	Limit func(queue string) int
+
+	// Tick, if set, runs on every poll before queued jobs are started (used
+	// for schedules). Returning true keeps the supervisor alive while the
+	// queues are empty.
+	Tick func(now time.Time) bool
+
+	Poll time.Duration // how often queues are checked; default 1s
+	Log  io.Writer     // start/failure messages; default io.Discard
+
+	// Locked means the caller already holds the supervisor lock (see
+	// LockSupervisor) and releases it. The supervisor then runs until ctx
+	// ends instead of exiting when idle.
+	Locked bool
+}
+
+// Supervise starts queued jobs while their queue has free slots. Only one
+// supervisor runs per state dir (see SupervisorPIDFileName); it returns nil
+// right away if another one holds the lock, and once no queued jobs are left
+// and Tick has no more work.
+func Supervise(ctx context.Context, st *Store, opt SuperviseOptions) error {
+	if opt.Limit == nil {
+		opt.Limit = func(string) int { return 1 }
+	}
+	if opt.Poll <= 0 {
+		opt.Poll = time.Second
+	}
+	if opt.Log == nil {
+		opt.Log = io.Discard
+	}
+
+	release := func() {}
+	if !opt.Locked {
+		var err error
+		if release, err = LockSupervisor(st); err != nil {
+			if errors.Is(err, fs.ErrExist) {
+				return nil
+			}
+			return err
+		}
+	}
+	defer func() { release() }()
+
+	tick := func() bool {
+		return opt.Tick != nil && opt.Tick(time.Now())
+	}
+
+	for {
+		busy := tick()
+		left, err := startQueued(st, opt)
+		if err != nil {
+			fmt.Fprintf(opt.Log, "supervisor: %v\n", err)
+		}
+		if err == nil && left == 0 && !busy && !opt.Locked {
+			// Release first, then look again: a job queued in between either
+			// sees no supervisor (and starts one) or is picked up here.
+			release()
+			release = func() {}
+			busy = tick()
+			if left, err = startQueued(st, opt); err != nil || left == 0 && !busy {
+				return err
+			}
+			if release, err = LockSupervisor(st); err != nil {
+				if errors.Is(err, fs.ErrExist) {
+					return nil
+				}
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(opt.Poll):
+		}
+	}
+}
+
+// startQueued starts queued jobs up to each queue's limit and returns how many
+// jobs are still queued.
+func startQueued(st *Store, opt SuperviseOptions) (int, error) {
+	ids, err := st.ListJobIDs()
+	if err != nil {
+		return 0, err
+	}
+
+	running := map[string]int{}
+	queued := map[string][]string{}
+	var order []string
+	for _, id := range ids {
+		m, err := st.Refresh(id)
+		if err != nil || m.Queue == "" {
+			continue
+		}
+		switch {
+		case m.Active():
+			running[m.Queue]++
+		case m.Status == StatusQueued:
+			if len(queued[m.Queue]) == 0 {
+				order = append(order, m.Queue)
+			}
+			queued[m.Queue] = append(queued[m.Queue], id)
+		}
+	}
+
+	left := 0
+	for _, q := range order {
+		free := opt.Limit(q) - running[q]
+		for _, id := range queued[q] {
+			if free <= 0 {
+				left++
+				continue
+			}
+			// Not ctx: runners must survive the supervisor.
+			res, err := StartJob(context.Background(), st, id, nil)
+			if err != nil {
+				fmt.Fprintf(opt.Log, "supervisor: start %s: %v\n", id, err)
+				continue
+			}
+			free--
+			fmt.Fprintf(opt.Log, "supervisor: started %s (queue=%s, pid=%d)\n", id, q, res.PID)
+		}
+	}
+	return left, nil
+}
+
+// LockSupervisor creates the supervisor pid file. It fails with fs.ErrExist
+// while another live supervisor holds it; stale files are replaced.
+func LockSupervisor(st *Store) (func(), error) {
+	path := filepath.Join(st.BaseDir, SupervisorPIDFileName)
+	pid := os.Getpid()
+	start, _ := processStartTime(pid)
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			_, werr := fmt.Fprintf(f, "%d %s\n", pid, start)
+			_ = f.Close()
+			if werr != nil {
+				_ = os.Remove(path)
+				return nil, fmt.Errorf("supervisor: write pid file: %w", werr)
+			}
+			return func() { _ = os.Remove(path) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("supervisor: pid file: %w", err)
+		}
+		if SupervisorRunning(st) {
+			return nil, fmt.Errorf("supervisor: already running: %w", fs.ErrExist)
+		}
+		_ = os.Remove(path)
+	}
+	return nil, fmt.Errorf("supervisor: pid file %s keeps reappearing: %w", path, fs.ErrExist)
+}
+
+// SupervisorRunning reports whether the pid file names a live supervisor.
+func SupervisorRunning(st *Store) bool {
+	_, ok := supervisorPID(st)
+	return ok
+}
+
+// supervisorPID returns the PID of the live supervisor, if any.
+func supervisorPID(st *Store) (int, bool) {
+	b, err := os.ReadFile(filepath.Join(st.BaseDir, SupervisorPIDFileName))
+	if err != nil {
+		return 0, false
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) == 0 {
+		return 0, false
+	}
+	pid, err := strconv.Atoi(fields[0])
+	if err != nil || pid <= 0 {
+		return 0, false
+	}
+	start := ""
+	if len(fields) > 1 {
+		start = fields[1]
+	}
+	return pid, processMatches(pid, start)
+}
+
+// StopSupervisor asks a running supervisor to exit and waits up to timeout
+// for it to release the pid file. Queued jobs and schedules are kept.
+func StopSupervisor(st *Store, timeout time.Duration) error {
+	pid, ok := supervisorPID(st)
+	if !ok {
+		return nil
+	}
+	if err := signalPID(pid, syscall.SIGTERM); err != nil {
+		return fmt.Errorf("supervisor: signal pid %d: %w", pid, err)
+	}
+	deadline := time.Now().Add(timeout)
+	for SupervisorRunning(st) {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("supervisor: pid %d did not exit after %s", pid, timeout)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return nil
+}
+
+// EnsureSupervisor starts a detached supervisor (runnerPath args...) unless
+// one is already running. Its output goes to SupervisorLogFileName.
+func EnsureSupervisor(st *Store, runnerPath string, args []string) error {
+	if SupervisorRunning(st) {
+		return nil
+	}
+	logFile, err := os.OpenFile(filepath.Join(st.BaseDir, SupervisorLogFileName),
+		os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("supervisor: open log: %w", err)
+	}
+	defer func() { _ = logFile.Close() }()
+
+	devNull, _ := os.Open(devNull()) // best-effort
+	defer func() {
+		if devNull != nil {
+			_ = devNull.Close()
+		}
+	}()
+
+	cmd := exec.Command(runnerPath, args...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	if devNull != nil {
+		cmd.Stdin = devNull
+	}
+	daemonize(cmd)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("supervisor: start: %w", err)
+	}
+	// Not waited for; like job runners, it outlives this process.
+	_ = cmd.Process.Release()
+	return nil
+}
@@ -0,0 +1,101 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type RerunOptions struct {
+	// RunnerPath is the runner executable (usually os.Executable()).
+	RunnerPath string
+
+	// ExtraArgs are runner flags inserted before the original "--", so they
+	// override the stored ones (e.g. ["--notify-each", "1m"]).
+	ExtraArgs []string
+
+	// Stop is used by restart to stop the job first if it is still running.
+	Stop *StopOptions
+
+	// Env is the runner environment (default: os.Environ()).
+	Env []string
+}
+
+// CmdRerun starts a new background job from a previous job's runner args and
+// working directory. The new job's ParentID points at the original.
+func CmdRerun(ctx context.Context, w io.Writer, st *Store, jobRef string, opt RerunOptions) (SpawnResult, error) {
+	if err := checkExtraArgs(opt.ExtraArgs); err != nil {
+		return SpawnResult{}, fmt.Errorf("rerun: %w", err)
+	}
+	jobID, err := ResolveJobRef(st, jobRef)
+	if err != nil {
+		return SpawnResult{}, fmt.Errorf("rerun: %w", err)
+	}
+	m, err := st.Refresh(jobID)
+	if err != nil {
+		return SpawnResult{}, err
+	}
+	if len(m.RunnerArgs) == 0 {
+		return SpawnResult{}, fmt.Errorf("rerun: job %s has no stored runner args", jobID)
+	}
+
+	if opt.Stop != nil && m.Active() {
+		if err := CmdStop(w, st, jobID, *opt.Stop); err != nil {
+			return SpawnResult{}, fmt.Errorf("restart: %w", err)
+		}
+	} else if opt.Stop == nil && m.Active() {
+		return SpawnResult{}, errors.New("rerun: job is still running (use job restart to stop it first)")
+	}
+
+	res, err := SpawnBackground(ctx, SpawnOptions{
+		RunnerPath:  opt.RunnerPath,
+		RunnerArgs:  insertRunnerArgs(m.RunnerArgs, opt.ExtraArgs),
+		Workdir:     m.Workdir,
+		Store:       st,
+		Command:     m.Command,
+		Tags:        m.Tags,
+		Name:        m.Name,
+		Labels:      m.Labels,
+		ParentID:    m.ID,
+		OutputFiles: m.OutputFiles,
+		Queue:       m.Queue,
+		Env:         opt.Env,
+	})
+	if err != nil {
+		return SpawnResult{}, err
+	}
+	if res.Meta.Status == StatusQueued {
+		fmt.Fprintf(w, "queued: %s (parent=%s, queue=%s)\n", res.JobID, m.ID, m.Queue)
+	} else {
+		fmt.Fprintf(w, "started: %s (parent=%s)\n", res.JobID, m.ID)
+	}
+	return res, nil
+}
+
+// checkExtraArgs rejects runner flags that would make the rerun's worker
+// spawn yet another job instead of running this one.
+func checkExtraArgs(extra []string) error {
+	for _, a := range extra {
+		name, _, _ := strings.Cut(a, "=")
+		if name == "--background" || name == "--queue" {
+			return fmt.Errorf("%s cannot be passed to a rerun (the job keeps its original queue)", name)
+		}
+	}
+	return nil
+}
+
+// insertRunnerArgs places extra before the first "--" of args (or at the end
+// when there is none), so later flags win over the stored ones.
+func insertRunnerArgs(args []string, extra []string) []string {
+	out := make([]string, 0, len(args)+len(extra))
+	for i, a := range args {
+		if a == "--" {
+			out = append(out, extra...)
+			return append(out, args[i:]...)
+		}
+		out = append(out, a)
+	}
+	return append(out, extra...)
+}
@@ -0,0 +1,171 @@
+package job
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// DefaultLogSegments is how many gzip segments RotateLog keeps by default.
+const DefaultLogSegments = 5
+
+// RotateLog moves the content of path into path.1.gz (shifting older segments
+// up to path.<keep>.gz) and truncates path in place. The runner keeps writing
+// to the same file descriptor in append mode, so copy+truncate is used
+// instead of rename.
+func RotateLog(path string, keep int) error {
+	if keep <= 0 {
+		keep = DefaultLogSegments
+	}
+
+	_ = os.Remove(segmentPath(path, keep))
+	for i := keep - 1; i >= 1; i-- {
+		_ = os.Rename(segmentPath(path, i), segmentPath(path, i+1))
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dstPath := segmentPath(path, 1)
+	dst, err := os.OpenFile(dstPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	err = copyUntilEnd(zw, src, path)
+	if err == nil {
+		err = zw.Close()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(dstPath)
+		return fmt.Errorf("rotate log: %w", err)
+	}
+
+	// Like logrotate's copytruncate, a line written between the last copy
+	// and the truncate can be lost; the window is a single stat call.
+	return os.Truncate(path, 0)
+}
+
+// copyUntilEnd copies src until it has caught up with the file size,
+// including output appended while copying.
+func copyUntilEnd(dst io.Writer, src *os.File, path string) error {
+	var n int64
+	for {
+		c, err := io.Copy(dst, src)
+		n += c
+		if err != nil {
+			return err
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if fi.Size() <= n {
+			return nil
+		}
+	}
+}
+
+func segmentPath(path string, n int) string {
+	return fmt.Sprintf("%s.%d.gz", path, n)
+}
+
+// RotateLogs rotates the job log whenever it exceeds maxBytes, checking every
+// interval, until the returned func is called.
+func (w *Worker) RotateLogs(maxBytes int64, keep int, interval time.Duration) func() {
+	if w == nil || maxBytes <= 0 {
+		return func() {}
+	}
+	m, err := w.st.ReadMeta(w.id)
+	if err != nil || m.LogPath == "" {
+		return func() {}
+	}
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-t.C:
+				if fi, err := os.Stat(m.LogPath); err == nil && fi.Size() > maxBytes {
+					_ = RotateLog(m.LogPath, keep)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-done
+	}
+}
+
+// OpenFullLog returns the whole job log: the rotated gzip segments, oldest
+// first, followed by the live log.
+func OpenFullLog(path string) (io.ReadCloser, error) {
+	var rs []io.Reader
+	var closers []io.Closer
+	closeAll := func() {
+		for _, c := range closers {
+			_ = c.Close()
+		}
+	}
+	for i := maxSegment(path); i >= 1; i-- {
+		f, err := os.Open(segmentPath(path, i))
+		if err != nil {
+			continue
+		}
+		closers = append(closers, f)
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("open log segment %d: %w", i, err)
+		}
+		closers = append(closers, zr)
+		rs = append(rs, zr)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	closers = append(closers, f)
+	rs = append(rs, f)
+	return multiReadCloser{Reader: io.MultiReader(rs...), close: closeAll}, nil
+}
+
+// maxSegment returns the highest existing segment number of path.
+func maxSegment(path string) int {
+	n := 0
+	for {
+		if _, err := os.Stat(segmentPath(path, n+1)); err != nil {
+			return n
+		}
+		n++
+	}
+}
+
+type multiReadCloser struct {
+	io.Reader
+	close func()
+}
+
+func (m multiReadCloser) Close() error {
+	m.close()
+	return nil
+}
@@ -0,0 +1,41 @@
+package job
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ParseSignal accepts a signal name ("TERM", "SIGTERM", "term") or number.
+func ParseSignal(s string) (syscall.Signal, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return syscall.SIGTERM, nil
+	}
+	if n, err := strconv.Atoi(s); err == nil && n > 0 {
+		return syscall.Signal(n), nil
+	}
+	if sig, ok := signalsByName[strings.TrimPrefix(s, "SIG")]; ok {
+		return sig, nil
+	}
+	return 0, fmt.Errorf("unknown signal %q", s)
+}
+
+func signalName(sig os.Signal) string {
+	if s, ok := sig.(syscall.Signal); ok {
+		for name, v := range signalsByName {
+			if v == s {
+				return "SIG" + name
+			}
+		}
+		return strconv.Itoa(int(s))
+	}
+	return sig.String()
+}
+
+// signalExitCode is the shell convention for a process killed by sig.
+func signalExitCode(sig syscall.Signal) int {
+	return 128 + int(sig)
+}
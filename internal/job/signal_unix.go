@@ -0,0 +1,50 @@
+//go:build !windows
+
+package job
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+var signalsByName = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+	"TERM": syscall.SIGTERM,
+}
+
+// signalJob sends sig to the job's process groups. The runner is a session
+// leader (see daemonize), so its PID is also its group ID; the target tool
+// runs in a group of its own (see execx), with anything it spawned.
+func signalJob(m Meta, sig syscall.Signal) error {
+	if m.ChildPID > 0 {
+		_ = signalChild(m.ChildPID, sig)
+	}
+	err := syscall.Kill(-m.PID, sig)
+	if errors.Is(err, syscall.ESRCH) {
+		// No such group (e.g. the runner is not a group leader): signal it directly.
+		err = syscall.Kill(m.PID, sig)
+		if errors.Is(err, syscall.ESRCH) {
+			return nil
+		}
+	}
+	return err
+}
+
+// signalChild sends sig to the target tool's process group.
+func signalChild(pid int, sig os.Signal) error {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return signalPID(pid, sig)
+	}
+	err := syscall.Kill(-pid, s)
+	if errors.Is(err, syscall.ESRCH) {
+		return signalPID(pid, sig)
+	}
+	return err
+}
@@ -0,0 +1,28 @@
+//go:build windows
+
+package job
+
+import (
+	"os"
+	"syscall"
+)
+
+var signalsByName = map[string]syscall.Signal{
+	"INT":  syscall.SIGINT,
+	"KILL": syscall.SIGKILL,
+	"TERM": syscall.SIGTERM,
+}
+
+// signalJob terminates the runner and the target tool. Windows cannot deliver
+// POSIX signals to a process group, so every signal ends up as a kill.
+func signalJob(m Meta, sig syscall.Signal) error {
+	if m.ChildPID > 0 {
+		_ = KillPID(m.ChildPID)
+	}
+	return KillPID(m.PID)
+}
+
+// signalChild signals the target tool (there are no process groups).
+func signalChild(pid int, sig os.Signal) error {
+	return signalPID(pid, sig)
+}
@@ -24,11 +24,33 @@ type SpawnOptions struct {
 	// ["--worker", "--command", "subfinder -d example.com", "--notify-each", "10s", ...]
 	RunnerArgs []string
 
-	// Working directory for the worker.
+	// Working directory for the worker (default: current directory).
+	// Recorded in meta so "job rerun" starts from the same place.
 	Workdir string
 
 	// Store persists metadata and the log path.
 	Store *Store
+
+	// Command describes the target command; Tags are free-form labels.
+	Command string
+	Tags    []string
+
+	// Name and Labels are user metadata (--job-name, --label).
+	Name   string
+	Labels map[string]string
+
+	// ParentID is set when re-running an earlier job.
+	ParentID string
+
+	// OutputFiles are absolute paths of files the job writes (--output, --event-output).
+	OutputFiles []string
+
+	// Queue (--queue) makes the job wait for a free slot instead of starting now.
+	Queue string
+
+	// Env is the runner environment (default: os.Environ()). It is not stored,
+	// so queued jobs get the environment of the process that starts them.
+	Env []string
 }
 
 type SpawnResult struct {
@@ -38,27 +60,99 @@ type SpawnResult struct {
 	LogPath string
 }
 
+// SpawnBackground creates a job and starts its runner detached from the
+// terminal. Jobs with a Queue are only recorded as queued; the supervisor
+// (see Supervise) starts them when their queue has a free slot.
 func SpawnBackground(ctx context.Context, opt SpawnOptions) (SpawnResult, error) {
+	meta, err := CreateJob(opt)
+	if err != nil {
+		return SpawnResult{}, err
+	}
+	if meta.Status == StatusQueued {
+		return SpawnResult{
+			JobID:   meta.ID,
+			Meta:    meta,
+			LogPath: meta.LogPath,
+		}, nil
+	}
+	return StartJob(ctx, opt.Store, meta.ID, opt.Env)
+}
+
+// CreateJob persists a new job without starting it: "queued" when opt.Queue
+// is set, "starting" otherwise.
+func CreateJob(opt SpawnOptions) (Meta, error) {
 	if opt.Store == nil {
-		return SpawnResult{}, errors.New("spawn: Store is nil")
+		return Meta{}, errors.New("spawn: Store is nil")
 	}
 	if strings.TrimSpace(opt.RunnerPath) == "" {
-		return SpawnResult{}, errors.New("spawn: RunnerPath is empty")
+		return Meta{}, errors.New("spawn: RunnerPath is empty")
 	}
 
 	jobID, err := newJobID()
 	if err != nil {
-		return SpawnResult{}, fmt.Errorf("spawn: job id: %w", err)
+		return Meta{}, fmt.Errorf("spawn: job id: %w", err)
 	}
 
 	if err := opt.Store.CreateJobDirs(jobID); err != nil {
-		return SpawnResult{}, fmt.Errorf("spawn: create dirs: %w", err)
+		return Meta{}, fmt.Errorf("spawn: create dirs: %w", err)
 	}
 
-	logPath := opt.Store.LogPath(jobID)
+	workdir := opt.Workdir
+	if strings.TrimSpace(workdir) == "" {
+		workdir, _ = os.Getwd()
+	}
+
+	now := time.Now().UTC()
+	meta := Meta{
+		ID:          jobID,
+		PID:         0,
+		StartedAt:   now,
+		Command:     opt.Command,
+		Tags:        append([]string(nil), opt.Tags...),
+		Name:        opt.Name,
+		Labels:      opt.Labels,
+		ParentID:    opt.ParentID,
+		OutputFiles: append([]string(nil), opt.OutputFiles...),
+		RunnerPath:  opt.RunnerPath,
+		RunnerArgs:  append([]string{}, opt.RunnerArgs...),
+		Workdir:     workdir,
+		LogPath:     opt.Store.LogPath(jobID),
+		Status:      StatusStarting,
+	}
+	if opt.Queue != "" {
+		meta.Queue = opt.Queue
+		meta.QueuedAt = &now
+		meta.Status = StatusQueued
+	}
+
+	if err := opt.Store.WriteMeta(meta); err != nil {
+		return Meta{}, fmt.Errorf("spawn: write meta (%s): %w", meta.Status, err)
+	}
+	return meta, nil
+}
+
+// StartJob launches the runner of a "starting" or "queued" job created by
+// CreateJob with env (nil = os.Environ()). Queued jobs move to "starting"
+// under the meta lock, so a job is never started twice.
+func StartJob(ctx context.Context, st *Store, jobID string, env []string) (SpawnResult, error) {
+	meta, err := st.UpdateMeta(jobID, func(m *Meta) error {
+		switch m.Status {
+		case StatusStarting:
+		case StatusQueued:
+			m.Status = StatusStarting
+			m.StartedAt = time.Now().UTC()
+		default:
+			return fmt.Errorf("spawn: job %s is %s", m.ID, m.Status)
+		}
+		return nil
+	})
+	if err != nil {
+		return SpawnResult{}, err
+	}
 
-	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	logFile, err := os.OpenFile(meta.LogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
 	if err != nil {
+		markStartFailed(st, jobID, err)
 		return SpawnResult{}, fmt.Errorf("spawn: open log: %w", err)
 	}
 	defer func() {
@@ -74,10 +168,17 @@ func SpawnBackground(ctx context.Context, opt SpawnOptions) (SpawnResult, error)
 		}
 	}()
 
-	cmd := exec.CommandContext(ctx, opt.RunnerPath, opt.RunnerArgs...)
-	if strings.TrimSpace(opt.Workdir) != "" {
-		cmd.Dir = opt.Workdir
+	cmd := exec.CommandContext(ctx, meta.RunnerPath, meta.RunnerArgs...)
+	cmd.Dir = meta.Workdir
+
+	// Tell the worker which job it runs, so it can record its own lifecycle.
+	if env == nil {
+		env = os.Environ()
 	}
+	cmd.Env = append(env[:len(env):len(env)],
+		EnvJobID+"="+jobID,
+		EnvStateDir+"="+st.BaseDir,
+	)
 
 	// Redirect output to log file.
 	cmd.Stdout = logFile
@@ -89,44 +190,49 @@ func SpawnBackground(ctx context.Context, opt SpawnOptions) (SpawnResult, error)
 	// Detach / run in background.
 	daemonize(cmd)
 
-	// Persist initial meta before start.
-	meta := Meta{
-		ID:         jobID,
-		PID:        0,
-		StartedAt:  time.Now().UTC(),
-		RunnerArgs: append([]string{}, opt.RunnerArgs...),
-		Workdir:    cmd.Dir,
-		LogPath:    logPath,
-		Status:     StatusStarting,
-	}
-
-	if err := opt.Store.WriteMeta(meta); err != nil {
-		return SpawnResult{}, fmt.Errorf("spawn: write meta (starting): %w", err)
-	}
-
 	if err := cmd.Start(); err != nil {
-		meta.Status = StatusFailed
-		meta.ErrorText = err.Error()
-		_ = opt.Store.WriteMeta(meta)
+		markStartFailed(st, jobID, err)
 		return SpawnResult{}, fmt.Errorf("spawn: start: %w", err)
 	}
 
-	meta.PID = cmd.Process.Pid
-	meta.Status = StatusRunning
-
-	if err := opt.Store.WriteMeta(meta); err != nil {
+	// The worker may already have updated its meta; only fill what is still missing.
+	pid := cmd.Process.Pid
+	start, _ := processStartTime(pid)
+	meta, err = st.UpdateMeta(jobID, func(m *Meta) error {
+		if m.PID == 0 {
+			m.PID = pid
+			m.PIDStart = start
+		}
+		if m.Status == StatusStarting {
+			m.Status = StatusRunning
+		}
+		return nil
+	})
+	if err != nil {
 		return SpawnResult{}, fmt.Errorf("spawn: write meta (running): %w", err)
 	}
 
-	// Do NOT wait. We return immediately to free the terminal.
+	// Do NOT wait. We return immediately to free the terminal; the goroutine
+	// only reaps the runner when this process outlives it (the supervisor).
+	go func() { _ = cmd.Wait() }()
 	return SpawnResult{
 		JobID:   jobID,
 		PID:     meta.PID,
 		Meta:    meta,
-		LogPath: logPath,
+		LogPath: meta.LogPath,
 	}, nil
 }
 
+func markStartFailed(st *Store, jobID string, err error) {
+	_, _ = st.UpdateMeta(jobID, func(m *Meta) error {
+		now := time.Now().UTC()
+		m.Status = StatusFailed
+		m.ErrorText = err.Error()
+		m.EndedAt = &now
+		return nil
+	})
+}
+
 func newJobID() (string, error) {
 	// 8 random bytes = 16 hex chars. Good enough for job IDs.
 	b := make([]byte, 8)
@@ -217,6 +323,13 @@ func FollowFile(ctx context.Context, w io.Writer, path string, poll time.Duratio
 		}
 		if err != nil {
 			if errors.Is(err, io.EOF) {
+				// The log was rotated (copy+truncate): start over from the top.
+				if pos, perr := f.Seek(0, io.SeekCurrent); perr == nil {
+					if fi, serr := f.Stat(); serr == nil && fi.Size() < pos {
+						_, _ = f.Seek(0, io.SeekStart)
+						continue
+					}
+				}
 				time.Sleep(poll)
 				continue
 			}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"sort"
@@ -15,6 +16,7 @@ const (
 	DefaultAppDirName = ".gorunandcallme"
 	DefaultJobsDir    = "jobs"
 	MetaFileName      = "meta.json"
+	MetaLockFileName  = "meta.lock"
 	LogFileName       = "output.log"
 )
 
@@ -26,13 +28,56 @@ const (
 	StatusFinished Status = "finished"
 	StatusFailed   Status = "failed"
 	StatusStopped  Status = "stopped"
+
+	// StatusLost marks a job whose runner disappeared without recording an
+	// exit (crash, SIGKILL, reboot).
+	StatusLost Status = "lost"
+
+	// StatusQueued marks a job waiting for a free slot in its --queue;
+	// StatusCanceled a queued job removed with "job cancel" before it started.
+	StatusQueued   Status = "queued"
+	StatusCanceled Status = "canceled"
 )
 
 type Meta struct {
 	ID        string    `json:"id"`
-	PID       int       `json:"pid"`
+	PID       int       `json:"pid"` // runner (gorunandcallme worker) PID
 	StartedAt time.Time `json:"started_at"`
 
+	// ChildPID is the PID of the target tool started by the runner.
+	ChildPID int `json:"child_pid,omitempty"`
+
+	// Process start-time fingerprints (Linux), used to tell a live job from a
+	// recycled PID. Empty when unavailable.
+	PIDStart      string `json:"pid_start,omitempty"`
+	ChildPIDStart string `json:"child_pid_start,omitempty"`
+
+	// Command is the target command as typed by the user (for display and --grep).
+	Command string   `json:"command,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+
+	// Name (--job-name) addresses the job in job subcommands; Labels
+	// (--label key=value) are free-form metadata for filtering and notifications.
+	Name   string            `json:"name,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// ParentID links a job started by "job rerun"/"job restart" to its original.
+	ParentID string `json:"parent_id,omitempty"`
+
+	// OutputFiles are files the job writes besides its log (--output,
+	// --event-output), included in "job export" bundles.
+	OutputFiles []string `json:"output_files,omitempty"`
+
+	// Queue (--queue) limits concurrency with other jobs of the same queue.
+	// QueuedAt is when the job was queued; StartedAt is set once it starts.
+	// QueuePosition is computed by "job list" (1 = next to start), never stored.
+	Queue         string     `json:"queue,omitempty"`
+	QueuedAt      *time.Time `json:"queued_at,omitempty"`
+	QueuePosition int        `json:"queue_position,omitempty"`
+
+	// RunnerPath is the runner executable, kept so queued jobs can be started later.
+	RunnerPath string `json:"runner_path,omitempty"`
+
 	// "Runner" is your own tool (gorunandcallme) command line used for the background worker,
 	// not the target security tool command itself.
 	RunnerArgs []string `json:"runner_args,omitempty"`
@@ -44,6 +89,7 @@ type Meta struct {
 	ExitCode  *int       `json:"exit_code,omitempty"`
 	EndedAt   *time.Time `json:"ended_at,omitempty"`
 	ErrorText string     `json:"error_text,omitempty"`
+	Signal    string     `json:"signal,omitempty"` // signal that stopped the job, if any
 }
 
 type Store struct {
@@ -109,6 +155,66 @@ func (s *Store) WriteMeta(m Meta) error {
 	return nil
 }
 
+// UpdateMeta applies fn to the current meta under a per-job lock, so the
+// spawner, the worker and job commands never overwrite each other's changes.
+func (s *Store) UpdateMeta(id string, fn func(m *Meta) error) (Meta, error) {
+	unlock, err := s.lockMeta(id)
+	if err != nil {
+		return Meta{}, err
+	}
+	defer unlock()
+
+	m, err := s.ReadMeta(id)
+	if err != nil {
+		return Meta{}, err
+	}
+	if err := fn(&m); err != nil {
+		return m, err
+	}
+	if err := s.WriteMeta(m); err != nil {
+		return m, err
+	}
+	return m, nil
+}
+
+// lockMeta takes an exclusive lock file next to meta.json.
+func (s *Store) lockMeta(id string) (func(), error) {
+	unlock, err := LockFile(filepath.Join(s.JobDir(id), MetaLockFileName))
+	if err != nil {
+		return nil, fmt.Errorf("lock meta: %w", err)
+	}
+	return unlock, nil
+}
+
+// LockFile takes an exclusive lock file at path, waiting up to 5s for it,
+// and returns the function releasing it. Locks older than 30s are considered
+// abandoned by a crashed process.
+func LockFile(path string) (func(), error) {
+	const (
+		lockWait  = 5 * time.Second
+		lockStale = 30 * time.Second
+	)
+	deadline := time.Now().Add(lockWait)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			_ = f.Close()
+			return func() { _ = os.Remove(path) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		if fi, err := os.Stat(path); err == nil && time.Since(fi.ModTime()) > lockStale {
+			_ = os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timeout waiting for %s", path)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
 func (s *Store) ReadMeta(id string) (Meta, error) {
 	path := s.MetaPath(id)
 	b, err := os.ReadFile(path)
@@ -133,7 +239,8 @@ func (s *Store) ListJobIDs() ([]string, error) {
 			continue
 		}
 		name := e.Name()
-		if strings.TrimSpace(name) == "" {
+		// Dot entries are staging dirs (e.g. .import-*), not jobs.
+		if strings.TrimSpace(name) == "" || strings.HasPrefix(name, ".") {
 			continue
 		}
 		ids = append(ids, name)
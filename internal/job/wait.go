@@ -0,0 +1,125 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// ExitTimeout is returned by CmdWait when the timeout expires (as timeout(1) does).
+const ExitTimeout = 124
+
+type WaitOptions struct {
+	Any     bool          // return when the first job ends (default: wait for all)
+	Timeout time.Duration // 0 = wait forever
+	Poll    time.Duration // meta polling interval; default 500ms
+}
+
+// CmdWait blocks until the referenced jobs end and prints a summary.
+// It returns the exit code to propagate:
+//   - --all: 0 if every job succeeded, else the code of the first failed job (argument order)
+//   - --any: the code of the first job that ended
+//   - ExitTimeout if the timeout expired first
+func CmdWait(ctx context.Context, w io.Writer, st *Store, refs []string, opt WaitOptions) (int, error) {
+	if len(refs) == 0 {
+		return 0, errors.New("wait: no jobs given")
+	}
+	if opt.Poll <= 0 {
+		opt.Poll = 500 * time.Millisecond
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if opt.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opt.Timeout)
+		defer cancel()
+	}
+
+	ids := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		id, err := ResolveJobRef(st, ref)
+		if err != nil {
+			return 0, fmt.Errorf("wait: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	metas := make([]Meta, len(ids))
+	firstDone := -1
+	for {
+		pending := 0
+		for i, id := range ids {
+			if metas[i].ID != "" && metas[i].Ended() {
+				continue
+			}
+			m, err := st.Refresh(id)
+			if err != nil {
+				return 0, fmt.Errorf("wait: %w", err)
+			}
+			metas[i] = m
+			if !m.Ended() {
+				pending++
+			} else if firstDone < 0 {
+				firstDone = i
+			}
+		}
+		if pending == 0 || opt.Any && firstDone >= 0 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			printWaitSummary(w, metas)
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				fmt.Fprintf(w, "timeout after %s; %d job(s) still running\n", opt.Timeout, pending)
+				return ExitTimeout, nil
+			}
+			return 1, ctx.Err()
+		case <-time.After(opt.Poll):
+		}
+	}
+
+	printWaitSummary(w, metas)
+
+	if opt.Any {
+		return jobExitCode(metas[firstDone]), nil
+	}
+	for _, m := range metas {
+		if code := jobExitCode(m); code != 0 {
+			return code, nil
+		}
+	}
+	return 0, nil
+}
+
+// jobExitCode maps an ended job to a process exit code.
+func jobExitCode(m Meta) int {
+	if m.ExitCode != nil {
+		return *m.ExitCode
+	}
+	if m.Status == StatusFinished {
+		return 0
+	}
+	return 1
+}
+
+func printWaitSummary(w io.Writer, metas []Meta) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, m := range metas {
+		exit := "-"
+		if m.Ended() {
+			exit = strconv.Itoa(jobExitCode(m))
+		}
+		name := m.Name
+		if name == "" {
+			name = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\texit=%s\t%s\n", m.ID, name, m.Status, exit, formatDuration(m.Duration()))
+	}
+	_ = tw.Flush()
+}
@@ -0,0 +1,224 @@
+package job
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// EnvJobID tells a spawned runner which job it executes. It is distinct
+	// from the GORUNANDCALLME_JOB_ID exported to exec sinks, so a script that
+	// runs gorunandcallme is not mistaken for the job's worker.
+	EnvJobID = "GORUNANDCALLME_WORKER_JOB_ID"
+
+	// EnvStateDir tells a spawned runner where the job store lives,
+	// independent of config loading in the worker.
+	EnvStateDir = "GORUNANDCALLME_WORKER_STATE_DIR"
+)
+
+// Worker is the runner side of a background job. It records its own
+// lifecycle (running, child PID, exit status, signals) in meta.json.
+type Worker struct {
+	st *Store
+	id string
+
+	mu       sync.Mutex
+	childPID int
+	signal   os.Signal
+	done     bool
+
+	mutedUntil  time.Time // cached by AlertsMuted
+	muteChecked time.Time
+}
+
+// AttachWorker returns the Worker for the current process when it was spawned
+// as a background job (see EnvJobID), or nil otherwise. It marks the job running.
+func AttachWorker() (*Worker, error) {
+	id := strings.TrimSpace(os.Getenv(EnvJobID))
+	if id == "" {
+		return nil, nil
+	}
+	st, err := NewStore(os.Getenv(EnvStateDir))
+	if err != nil {
+		return nil, err
+	}
+
+	// Do not leak the job identity to the tool we run.
+	_ = os.Unsetenv(EnvJobID)
+	_ = os.Unsetenv(EnvStateDir)
+
+	w := &Worker{st: st, id: id}
+	pid := os.Getpid()
+	start, _ := processStartTime(pid)
+	_, err = st.UpdateMeta(id, func(m *Meta) error {
+		m.PID = pid
+		m.PIDStart = start
+		m.Status = StatusRunning
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("worker: %w", err)
+	}
+	return w, nil
+}
+
+func (w *Worker) JobID() string {
+	if w == nil {
+		return ""
+	}
+	return w.id
+}
+
+func (w *Worker) Store() *Store {
+	if w == nil {
+		return nil
+	}
+	return w.st
+}
+
+// Meta returns the job's current metadata.
+func (w *Worker) Meta() (Meta, error) {
+	if w == nil {
+		return Meta{}, errors.New("worker: not a background job")
+	}
+	return w.st.ReadMeta(w.id)
+}
+
+// ChildStarted records the PID of the target tool.
+func (w *Worker) ChildStarted(pid int) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	w.childPID = pid
+	w.mu.Unlock()
+
+	start, _ := processStartTime(pid)
+	_, _ = w.st.UpdateMeta(w.id, func(m *Meta) error {
+		m.ChildPID = pid
+		m.ChildPIDStart = start
+		return nil
+	})
+}
+
+// HandleSignals records SIGINT/SIGTERM/SIGHUP/SIGQUIT and forwards them to the
+// child, so the runner keeps running long enough to flush notifications and
+// record the exit. Signals sent by `job stop` already reach the whole process
+// group and are not forwarded again. The returned func stops signal handling.
+func (w *Worker) HandleSignals() func() {
+	if w == nil {
+		return func() {}
+	}
+	ch := make(chan os.Signal, 2)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT)
+	stop := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case sig := <-ch:
+				w.mu.Lock()
+				w.signal = sig
+				pid := w.childPID
+				w.mu.Unlock()
+
+				// job stop records its signal before sending it to the groups.
+				forward := true
+				_, _ = w.st.UpdateMeta(w.id, func(m *Meta) error {
+					forward = m.Signal == ""
+					m.Signal = signalName(sig)
+					return nil
+				})
+				if forward && pid > 0 {
+					_ = signalChild(pid, sig)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(ch)
+		close(stop)
+	}
+}
+
+// Signaled returns the signal the runner received, or nil.
+func (w *Worker) Signaled() os.Signal {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.signal
+}
+
+// Finish records the final status. It is safe to call more than once;
+// only the first call is recorded.
+func (w *Worker) Finish(exitCode int, runErr error) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	if w.done {
+		w.mu.Unlock()
+		return
+	}
+	w.done = true
+	sig := w.signal
+	w.mu.Unlock()
+
+	now := time.Now().UTC()
+	_, _ = w.st.UpdateMeta(w.id, func(m *Meta) error {
+		m.EndedAt = &now
+		code := exitCode
+		m.ExitCode = &code
+		switch {
+		case sig != nil:
+			m.Status = StatusStopped
+			m.Signal = signalName(sig)
+		case runErr != nil || exitCode != 0:
+			m.Status = StatusFailed
+		default:
+			m.Status = StatusFinished
+		}
+		if runErr != nil {
+			m.ErrorText = runErr.Error()
+		}
+		return nil
+	})
+}
+
+// Recover marks the job failed if the runner panics, then re-panics.
+// Use as: defer worker.Recover()
+func (w *Worker) Recover() {
+	if w == nil {
+		return
+	}
+	if r := recover(); r != nil {
+		w.Finish(1, fmt.Errorf("runner panic: %v", r))
+		panic(r)
+	}
+}
+
+// signalPID delivers sig to pid; platforms without signal support fall back to Kill.
+func signalPID(pid int, sig os.Signal) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	if err := p.Signal(sig); err != nil {
+		if errors.Is(err, os.ErrProcessDone) {
+			return nil
+		}
+		return p.Kill()
+	}
+	return nil
+}
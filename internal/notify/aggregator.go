@@ -2,6 +2,7 @@ package notify
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -17,15 +18,42 @@ type AggregatorOptions struct {
 	Alerts   *Alerts
 	UI       UI
 	Dispatch *Dispatcher
+
+	// JobID identifies the run in incident dedup keys and structured messages.
+	JobID string
+
+	// Command is the target command, attached to structured messages.
+	Command string
+
+	// JobName and Labels describe background jobs (--job-name, --label)
+	// and are included in lifecycle notifications.
+	JobName string
+	Labels  map[string]string
+
+	// AlertsMuted drops alerts while it returns true ("Mute alerts" button).
+	AlertsMuted func() bool
+
+	// JobControls marks messages of background jobs for interactive buttons.
+	JobControls bool
+
+	// OnStall is called once per stall when Config.KillOnStall is set, with
+	// how long the command has been silent.
+	OnStall func(idle time.Duration)
 }
 
 type Aggregator struct {
-	cfg   config.NotifyConfig
-	ui    UI
-	disp  *Dispatcher
-	red   *Redactor
-	filt  *Filters
-	alert *Alerts
+	cfg     config.NotifyConfig
+	ui      UI
+	disp    *Dispatcher
+	red     *Redactor
+	filt    *Filters
+	alert   *Alerts
+	jobID   string
+	command string
+	jobName string
+	labels  map[string]string
+	muted   func() bool
+	control bool
 
 	mu       sync.Mutex
 	lines    []string
@@ -33,31 +61,66 @@ type Aggregator struct {
 	lastTick time.Time
 	ticker   *time.Ticker
 	stop     chan struct{}
+
+	throttle  *alertThrottle            // nil when alerts are not throttled
+	rules     map[*AlertRule]*ruleState // min_count and absence state
+	incidents map[string]Incident       // dedup key -> last triggered incident
+	alerting  sync.WaitGroup            // in-flight sendAlert goroutines
+
+	// Activity for --heartbeat and --stall-after, updated by OnLine.
+	heartbeat  time.Duration
+	stallAfter time.Duration
+	onStall    func(idle time.Duration)
+	started    time.Time
+	lastOutput time.Time
+	lastLine   string
+	seen       int64
+	stalled    bool
+
+	stderr []string // last failure_lines stderr lines, for failure messages
 }
 
 func NewAggregator(o AggregatorOptions) (*Aggregator, error) {
 	a := &Aggregator{
-		cfg:    o.Config,
-		ui:     o.UI,
-		disp:   o.Dispatch,
-		red:    o.Redactor,
-		filt:   o.Filters,
-		alert:  o.Alerts,
-		stop:   make(chan struct{}),
-		lines:  nil,
+		cfg:     o.Config,
+		ui:      o.UI,
+		disp:    o.Dispatch,
+		red:     o.Redactor,
+		filt:    o.Filters,
+		alert:   o.Alerts,
+		jobID:   o.JobID,
+		command: o.Command,
+		jobName: o.JobName,
+		labels:  o.Labels,
+		muted:   o.AlertsMuted,
+		control: o.JobControls,
+		stop:    make(chan struct{}),
+		lines:   nil,
 		context: nil,
+		onStall: o.OnStall,
+		started: time.Now(),
 	}
+	a.lastOutput = a.started
 
 	if a.cfg.NotifyEach != "" {
 		d, err := util.ParseExtendedDuration(a.cfg.NotifyEach)
 		if err != nil {
 			return nil, err
 		}
-		if d > 0 {
+		// With quiet_success, output is only sent once the run failed.
+		if d > 0 && !a.cfg.QuietSuccess {
 			a.ticker = time.NewTicker(d)
 			go a.loop()
 		}
 	}
+	throttle, err := newAlertThrottle(a.cfg.Alerts)
+	if err != nil {
+		return nil, err
+	}
+	a.throttle = throttle
+	if err := a.startWatch(); err != nil {
+		return nil, err
+	}
 	return a, nil
 }
 
@@ -98,6 +161,10 @@ func (a *Aggregator) OnLine(stream string, line string) {
 	if a.red != nil {
 		line = a.red.Apply(line)
 	}
+	a.touch(line)
+	if stream == "stderr" {
+		a.keepStderr(line)
+	}
 	if a.filt != nil && !a.filt.Allow(line) {
 		return
 	}
@@ -111,23 +178,101 @@ func (a *Aggregator) OnLine(stream string, line string) {
 		a.context = a.context[len(a.context)-a.alert.ContextLines():]
 	}
 
+	now := time.Now()
+	a.seenAbsent(line, now)
+
 	// Immediate alert on match
-	if a.alert != nil && a.alert.Match(line) {
-		ctx := append([]string{}, a.context...)
-		go a.sendAlert(line, ctx)
+	if r := a.alert.MatchRule(line); r != nil && !a.alertsMuted() && a.reached(r, now) && a.throttle.admit(r, line, now) {
+		ctx := a.context
+		if r.ContextLines > 0 && len(ctx) > r.ContextLines {
+			ctx = ctx[len(ctx)-r.ContextLines:]
+		}
+		ctx = append([]string{}, ctx...)
+		a.alerting.Add(1)
+		go func() {
+			defer a.alerting.Done()
+			a.sendAlert(r, line, ctx)
+		}()
 	}
 }
 
-func (a *Aggregator) sendAlert(matched string, ctx []string) {
-	title := fmt.Sprintf("ALERT: matched output pattern")
+func (a *Aggregator) alertsMuted() bool {
+	return a.muted != nil && a.muted()
+}
+
+func (a *Aggregator) sendAlert(r *AlertRule, matched string, ctx []string) {
+	what := "matched output pattern"
+	if r.MinCount > 1 {
+		what = fmt.Sprintf("%d matches", r.MinCount)
+		if r.Window > 0 {
+			what += " within " + r.Window.String()
+		}
+	}
 	body := "Matched:\n" + matched + "\n\nContext:\n" + JoinLines(ctx)
-	a.sendText(title, body)
+	m := a.newAlert(r, alertTitle(r, what), body)
+	m.Lines = ctx
+	m.Fields["matched"] = matched
+	a.send(m)
+	a.triggerIncident(r, matched, body)
+}
+
+func (a *Aggregator) triggerIncident(r *AlertRule, matched string, details string) {
+	if a == nil || a.disp == nil {
+		return
+	}
+	inc := Incident{
+		DedupKey:     IncidentDedupKey(a.jobID, r.Label()),
+		JobID:        a.jobID,
+		Pattern:      r.Label(),
+		Matched:      matched,
+		Summary:      "gorunandcallme alert: " + matched,
+		Details:      details,
+		Destinations: r.Destinations,
+	}
+	if r.severitySet {
+		inc.Severity = r.Severity
+	}
+
+	a.mu.Lock()
+	if a.incidents == nil {
+		a.incidents = map[string]Incident{}
+	}
+	a.incidents[inc.DedupKey] = inc
+	a.mu.Unlock()
+
+	_ = a.disp.BroadcastIncident(inc, false)
+}
+
+// ResolveIncidents resolves every incident triggered during this run.
+// Clients decide individually whether they honor resolves (resolve_on_finish).
+func (a *Aggregator) ResolveIncidents() {
+	if a == nil || a.disp == nil {
+		return
+	}
+	// Triggers must be queued before their resolves.
+	a.alerting.Wait()
+
+	a.mu.Lock()
+	incs := make([]Incident, 0, len(a.incidents))
+	for _, inc := range a.incidents {
+		incs = append(incs, inc)
+	}
+	a.incidents = nil
+	a.mu.Unlock()
+
+	for _, inc := range incs {
+		_ = a.disp.BroadcastIncident(inc, true)
+	}
 }
 
 func (a *Aggregator) FlushAll(reason string) {
 	if a == nil || a.disp == nil {
 		return
 	}
+	if reason == "final" {
+		a.checkAbsent(true)
+		a.flushDigests(true)
+	}
 
 	a.mu.Lock()
 	lines := append([]string{}, a.lines...)
@@ -143,7 +288,9 @@ func (a *Aggregator) FlushAll(reason string) {
 	switch mode {
 	case "summary":
 		txt := Summary(lines, 30)
-		a.sendText("Output summary", txt)
+		m := a.newMessage(KindBatch, "Output summary", txt)
+		m.Lines = lines
+		a.send(m)
 		return
 	}
 
@@ -159,14 +306,16 @@ func (a *Aggregator) FlushAll(reason string) {
 	}
 
 	text := JoinLines(selected)
+	batch := a.newMessage(KindBatch, "Output batch", text)
+	batch.Lines = selected
 
 	switch mode {
 	case "text-only":
-		a.sendText("Output batch", text)
+		a.send(batch)
 		return
 	case "attach-only":
 		if !a.cfg.Attach.Enabled {
-			_ = a.disp.BroadcastText(WrapCodeBlockMarkdown(text))
+			a.send(batch)
 			return
 		}
 		a.sendAttach("Output batch", lines)
@@ -177,23 +326,66 @@ func (a *Aggregator) FlushAll(reason string) {
 			a.sendAttach("Output batch", lines)
 			return
 		}
-		a.sendText("Output batch", text)
+		a.send(batch)
 		return
 	}
 }
 
-func (a *Aggregator) SendLifecycle(state string, fullCmd string, details string) {
-	title := fmt.Sprintf("Job %s", state)
-	msg := fmt.Sprintf("%s `%s`\n%s", strings.Title(state), fullCmd, details)
-	a.sendText(title, msg)
+// SendLifecycle notifies a lifecycle state change. exitCode is nil until the job finished.
+func (a *Aggregator) SendLifecycle(state string, fullCmd string, details string, exitCode *int) {
+	a.sendLifecycle(state, a.jobTitle(state), fmt.Sprintf("%s `%s`\n%s", strings.Title(state), fullCmd, details), exitCode, nil)
+}
+
+// sendLifecycle sends a lifecycle message, adding the job name and labels to
+// the body and to fields.
+func (a *Aggregator) sendLifecycle(state string, title string, msg string, exitCode *int, fields map[string]string) {
+	keys := make([]string, 0, len(a.labels))
+	for k := range a.labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if len(keys) > 0 {
+		pairs := make([]string, 0, len(keys))
+		for _, k := range keys {
+			pairs = append(pairs, k+"="+a.labels[k])
+		}
+		msg += "\nlabels: " + strings.Join(pairs, " ")
+	}
+
+	m := a.newMessage(KindLifecycle, title, msg)
+	m.State = state
+	m.ExitCode = exitCode
+	if a.jobName != "" || len(keys) > 0 || len(fields) > 0 {
+		m.Fields = map[string]string{}
+		for k, v := range fields {
+			m.Fields[k] = v
+		}
+		if a.jobName != "" {
+			m.Fields["job_name"] = a.jobName
+		}
+		for _, k := range keys {
+			m.Fields["label."+k] = a.labels[k]
+		}
+	}
+	a.send(m)
+}
+
+func (a *Aggregator) newMessage(kind string, title string, body string) Message {
+	return Message{
+		Kind:        kind,
+		JobID:       a.jobID,
+		Command:     a.command,
+		Title:       title,
+		Body:        body,
+		JobControls: a.control,
+	}
 }
 
-func (a *Aggregator) sendText(title string, text string) {
+func (a *Aggregator) send(m Message) {
 	if a == nil || a.disp == nil {
 		return
 	}
-	payload := title + "\n" + WrapCodeBlockMarkdown(text)
-	_ = a.disp.BroadcastText(payload)
+	_ = a.disp.BroadcastMessage(m)
 }
 
 func (a *Aggregator) sendAttach(title string, lines []string) {
@@ -214,18 +406,23 @@ func (a *Aggregator) sendAttach(title string, lines []string) {
 	if v, ok := a.cfg.Attach.PartMaxBytes["telegram"]; ok && v > 0 && v < max {
 		max = v
 	}
+	// Clients with a hard payload limit (e.g. NATS) cap every part.
+	if v := a.disp.MaxAttachBytes(); v > 0 && v < max {
+		max = v
+	}
 
 	switch strings.ToLower(a.cfg.Attach.SplitMode) {
 	case "tail":
 		lines = TailLines(lines, a.cfg.Attach.TailLines)
 		data := []byte(JoinLines(lines) + "\n")
-		_ = a.disp.BroadcastFile("output.log", "text/plain", data, title)
+		_ = a.disp.BroadcastMessageFile(a.newMessage(KindBatch, title, ""), "output.log", "text/plain", data)
 		return
 	default: // split
 		parts := BuildAttachmentParts(lines, max)
 		for i, p := range parts {
 			caption := fmt.Sprintf("%s (part %d/%d)", title, i+1, len(parts))
-			_ = a.disp.BroadcastFile(fmt.Sprintf("output.part.%03d.log", i+1), "text/plain", p, caption)
+			m := a.newMessage(KindBatch, caption, "")
+			_ = a.disp.BroadcastMessageFile(m, fmt.Sprintf("output.part.%03d.log", i+1), "text/plain", p)
 		}
 		return
 	}
@@ -0,0 +1,315 @@
+package notify
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/haltman-io/gorunandcallme/internal/config"
+	"github.com/haltman-io/gorunandcallme/internal/util"
+)
+
+// Alert severities.
+const (
+	SeverityInfo     = "info"
+	SeverityWarn     = "warn"
+	SeverityCritical = "critical"
+)
+
+// AlertRule is a compiled config.AlertRule.
+type AlertRule struct {
+	Name         string
+	Severity     string
+	ContextLines int
+	MinCount     int
+	Window       time.Duration
+	Absent       bool
+	Destinations []string
+
+	re          *regexp.Regexp
+	severitySet bool // severity came from the config, not the default
+}
+
+func (r *AlertRule) Pattern() string { return r.re.String() }
+
+// Label names the rule in messages and incident keys: its name, or its
+// pattern for unnamed rules.
+func (r *AlertRule) Label() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.re.String()
+}
+
+type Alerts struct {
+	rules   []*AlertRule
+	context int
+}
+
+// NewAlerts compiles cfg.Patterns followed by cfg.Rules.
+func NewAlerts(cfg config.AlertsConfig) (*Alerts, error) {
+	a := &Alerts{context: cfg.IncludeContextLines}
+	for _, p := range cfg.Patterns {
+		if err := a.add(config.AlertRule{Pattern: p}); err != nil {
+			return nil, err
+		}
+	}
+	for _, r := range cfg.Rules {
+		if err := a.add(r); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+func (a *Alerts) add(c config.AlertRule) error {
+	label := c.Name
+	if label == "" {
+		label = c.Pattern
+	}
+	if c.Pattern == "" {
+		return fmt.Errorf("alert rule %q: pattern is required", label)
+	}
+	re, err := regexp.Compile(c.Pattern)
+	if err != nil {
+		return fmt.Errorf("alert rule %q: %w", label, err)
+	}
+	r := &AlertRule{
+		Name:         c.Name,
+		Severity:     strings.ToLower(strings.TrimSpace(c.Severity)),
+		ContextLines: c.ContextLines,
+		MinCount:     c.MinCount,
+		Absent:       c.Absent,
+		re:           re,
+	}
+	switch r.Severity {
+	case "":
+		r.Severity = SeverityWarn
+	case SeverityInfo, SeverityWarn, SeverityCritical:
+		r.severitySet = true
+	default:
+		return fmt.Errorf("alert rule %q: severity must be info|warn|critical", label)
+	}
+	if r.ContextLines < 0 || r.MinCount < 0 {
+		return fmt.Errorf("alert rule %q: context_lines and min_count must not be negative", label)
+	}
+	if r.ContextLines == 0 {
+		r.ContextLines = a.context
+	}
+	if r.MinCount == 0 {
+		r.MinCount = 1
+	}
+	if c.Window != "" {
+		if r.Window, err = util.ParseExtendedDuration(c.Window); err != nil {
+			return fmt.Errorf("alert rule %q: invalid window: %w", label, err)
+		}
+	}
+	for _, d := range c.Destinations {
+		d = strings.ToLower(strings.TrimSpace(d))
+		if !slices.Contains(callbackNames, d) {
+			return fmt.Errorf("alert rule %q: unknown destination %q", label, d)
+		}
+		r.Destinations = append(r.Destinations, d)
+	}
+	a.rules = append(a.rules, r)
+	return nil
+}
+
+func (a *Alerts) Match(line string) bool {
+	return a.MatchRule(line) != nil
+}
+
+// MatchRule returns the first rule matching line. Absence rules never match.
+func (a *Alerts) MatchRule(line string) *AlertRule {
+	if a == nil {
+		return nil
+	}
+	for _, r := range a.rules {
+		if !r.Absent && r.re.MatchString(line) {
+			return r
+		}
+	}
+	return nil
+}
+
+// LineFields returns the fields marking a line event that matches a rule:
+// the rule label in "alert" and its severity in "severity". It returns nil
+// for other lines.
+func (a *Alerts) LineFields(line string) map[string]string {
+	r := a.MatchRule(util.StripANSI(line))
+	if r == nil {
+		return nil
+	}
+	return map[string]string{"alert": r.Label(), "severity": r.Severity}
+}
+
+// MatchPattern returns the label of the first rule matching line.
+func (a *Alerts) MatchPattern(line string) (string, bool) {
+	r := a.MatchRule(line)
+	if r == nil {
+		return "", false
+	}
+	return r.Label(), true
+}
+
+// ContextLines is the number of lines the longest rule context needs.
+func (a *Alerts) ContextLines() int {
+	if a == nil {
+		return 0
+	}
+	n := a.context
+	for _, r := range a.rules {
+		n = max(n, r.ContextLines)
+	}
+	return n
+}
+
+func (a *Alerts) absenceRules() []*AlertRule {
+	if a == nil {
+		return nil
+	}
+	var out []*AlertRule
+	for _, r := range a.rules {
+		if r.Absent {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// absenceWindow returns the shortest window of the absence rules, or 0.
+func (a *Alerts) absenceWindow() time.Duration {
+	var w time.Duration
+	for _, r := range a.absenceRules() {
+		if r.Window > 0 && (w == 0 || r.Window < w) {
+			w = r.Window
+		}
+	}
+	return w
+}
+
+// ruleState is the per-run state of a rule, guarded by Aggregator.mu.
+type ruleState struct {
+	hits  []time.Time // matches counting toward min_count
+	last  time.Time   // absence rules: last match, or the start of the run
+	seen  bool        // absence rules: matched at least once
+	fired bool        // absence rules: alerted for the current silence
+}
+
+func (a *Aggregator) ruleState(r *AlertRule) *ruleState {
+	if a.rules == nil {
+		a.rules = map[*AlertRule]*ruleState{}
+	}
+	st := a.rules[r]
+	if st == nil {
+		st = &ruleState{last: a.started}
+		a.rules[r] = st
+	}
+	return st
+}
+
+// reached counts a match of r and reports whether min_count matches were
+// seen within the window, which starts the count over.
+func (a *Aggregator) reached(r *AlertRule, now time.Time) bool {
+	if r.MinCount <= 1 {
+		return true
+	}
+	st := a.ruleState(r)
+	if r.Window > 0 {
+		i := 0
+		for i < len(st.hits) && now.Sub(st.hits[i]) >= r.Window {
+			i++
+		}
+		st.hits = st.hits[i:]
+	}
+	st.hits = append(st.hits, now)
+	if len(st.hits) < r.MinCount {
+		return false
+	}
+	st.hits = nil
+	return true
+}
+
+// seenAbsent records matches of absence rules.
+func (a *Aggregator) seenAbsent(line string, now time.Time) {
+	for _, r := range a.alert.absenceRules() {
+		if r.re.MatchString(line) {
+			st := a.ruleState(r)
+			st.last, st.seen, st.fired = now, true, false
+		}
+	}
+}
+
+// checkAbsent alerts on absence rules: windowed rules once per silence
+// longer than their window, the others at the end of the run when their
+// pattern never matched.
+func (a *Aggregator) checkAbsent(final bool) {
+	now := time.Now()
+	type absence struct {
+		r     *AlertRule
+		since time.Duration
+	}
+	var fire []absence
+	a.mu.Lock()
+	for _, r := range a.alert.absenceRules() {
+		st := a.ruleState(r)
+		switch {
+		case r.Window > 0 && !final:
+			if since := now.Sub(st.last); !st.fired && since >= r.Window {
+				st.fired = true
+				fire = append(fire, absence{r, since})
+			}
+		case r.Window <= 0 && final:
+			if !st.seen {
+				fire = append(fire, absence{r, 0})
+			}
+		}
+	}
+	a.mu.Unlock()
+	if a.alertsMuted() {
+		return
+	}
+
+	for _, f := range fire {
+		what, body := "pattern never matched", "Not seen during the run."
+		if f.since > 0 {
+			what = "no match for " + roundSecond(f.since)
+			body = fmt.Sprintf("Not seen for %s.", roundSecond(f.since))
+		}
+		body = "Pattern:\n" + f.r.Pattern() + "\n\n" + body
+		m := a.newAlert(f.r, alertTitle(f.r, what), body)
+		m.Fields["absent"] = "true"
+		a.send(m)
+		a.triggerIncident(f.r, "(absent) "+f.r.Pattern(), body)
+	}
+}
+
+// newAlert builds an alert message carrying the rule's severity, routing
+// and identification fields.
+func (a *Aggregator) newAlert(r *AlertRule, title, body string) Message {
+	m := a.newMessage(KindAlert, title, body)
+	m.Severity = r.Severity
+	m.Destinations = r.Destinations
+	m.Fields = map[string]string{"pattern": r.Pattern()}
+	if r.Name != "" {
+		m.Fields["rule"] = r.Name
+	}
+	return m
+}
+
+// alertTitle renders e.g. "ALERT [CRITICAL] disk-full: matched output pattern".
+func alertTitle(r *AlertRule, what string) string {
+	t := "ALERT [" + strings.ToUpper(r.Severity) + "]"
+	if r.Name != "" {
+		t += " " + r.Name
+	}
+	return t + ": " + what
+}
+
+// sortRules orders rules by label, for stable digests.
+func sortRules(rules []*AlertRule) {
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Label() < rules[j].Label() })
+}
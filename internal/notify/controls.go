@@ -0,0 +1,44 @@
+package notify
+
+import "strings"
+
+// Job control actions behind the interactive Slack/Discord buttons, handled
+// by "control receiver".
+const (
+	ActionStop    = "stop"
+	ActionSendLog = "sendlog"
+	ActionMute    = "mute"
+)
+
+type jobControl struct {
+	Action string
+	Label  string
+	Danger bool
+}
+
+// jobControls returns the buttons for m: lifecycle and alert messages of
+// background jobs only, and no Stop or Mute once the job has finished.
+func jobControls(m Message) []jobControl {
+	if !m.JobControls || m.JobID == "" || m.Kind != KindLifecycle && m.Kind != KindAlert {
+		return nil
+	}
+	var out []jobControl
+	if m.ExitCode == nil {
+		out = append(out, jobControl{Action: ActionStop, Label: "Stop job", Danger: true})
+	}
+	out = append(out, jobControl{Action: ActionSendLog, Label: "Send full log"})
+	if m.ExitCode == nil {
+		out = append(out, jobControl{Action: ActionMute, Label: "Mute alerts 1h"})
+	}
+	return out
+}
+
+// ControlID is the Discord custom_id of a job control button.
+func ControlID(action, jobID string) string {
+	return action + ":" + jobID
+}
+
+// ParseControlID splits a Discord custom_id built by ControlID.
+func ParseControlID(id string) (action, jobID string, ok bool) {
+	return strings.Cut(id, ":")
+}
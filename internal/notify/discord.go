@@ -12,16 +12,18 @@ import (
 type DiscordClient struct {
 	http *http.Client
 	hook string
+
+	interactive bool // job control buttons on lifecycle and alert messages
 }
 
-func NewDiscordClient(httpc *http.Client, webhookURL string, maxAttachBytes int) (*DiscordClient, error) {
+func NewDiscordClient(httpc *http.Client, webhookURL string, maxAttachBytes int, interactive bool) (*DiscordClient, error) {
 	if webhookURL == "" {
 		return nil, errors.New("discord webhook url is empty")
 	}
 	if _, err := url.Parse(webhookURL); err != nil {
 		return nil, err
 	}
-	return &DiscordClient{http: httpc, hook: webhookURL}, nil
+	return &DiscordClient{http: httpc, hook: webhookURL, interactive: interactive}, nil
 }
 
 func (d *DiscordClient) Name() string { return "discord" }
@@ -29,21 +31,64 @@ func (d *DiscordClient) MaxTextChars() int { return 1900 } // keep margin under
 func (d *DiscordClient) MaxAttachBytes() int { return 8000000 }
 
 func (d *DiscordClient) SendText(text string) error {
-	body := map[string]any{
+	return d.post(d.hook, map[string]any{
 		"content": text,
+	})
+}
+
+// SendMessage adds a row of buttons for the job controls when interactive
+// controls are enabled; each custom_id is ControlID(action, job ID).
+func (d *DiscordClient) SendMessage(m Message) error {
+	controls := jobControls(m)
+	if !d.interactive || len(controls) == 0 {
+		return d.SendText(m.Text())
 	}
+	buttons := make([]map[string]any, 0, len(controls))
+	for _, c := range controls {
+		style := 2 // secondary
+		if c.Danger {
+			style = 4
+		}
+		buttons = append(buttons, map[string]any{
+			"type":      2,
+			"style":     style,
+			"label":     c.Label,
+			"custom_id": ControlID(c.Action, m.JobID),
+		})
+	}
+	// Only webhooks created by the application whose interactions endpoint
+	// runs "control receiver" can carry custom_id buttons; Discord rejects
+	// them on channel webhooks, so those get the plain message instead.
+	status, err := d.postStatus(d.hook, map[string]any{
+		"content":    m.Text(),
+		"components": []map[string]any{{"type": 1, "components": buttons}},
+	})
+	if err != nil && status >= 400 && status < 500 {
+		return d.SendText(m.Text())
+	}
+	return err
+}
+
+func (d *DiscordClient) post(hook string, body map[string]any) error {
+	_, err := d.postStatus(hook, body)
+	return err
+}
+
+// postStatus posts body and returns the response status code (0 when the
+// request failed).
+func (d *DiscordClient) postStatus(hook string, body map[string]any) (int, error) {
 	b, _ := json.Marshal(body)
-	req, _ := http.NewRequest("POST", d.hook, bytes.NewReader(b))
+	req, _ := http.NewRequest("POST", hook, bytes.NewReader(b))
 	req.Header.Set("Content-Type", "application/json")
 	resp, err := d.http.Do(req)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode >= 300 {
-		return errors.New(resp.Status)
+		return resp.StatusCode, errors.New(resp.Status)
 	}
-	return nil
+	return resp.StatusCode, nil
 }
 
 func (d *DiscordClient) SendFile(filename string, contentType string, data []byte, caption string) error {
@@ -3,6 +3,7 @@ package notify
 import (
 	"errors"
 	"net/http"
+	"slices"
 	"sync"
 	"time"
 
@@ -18,6 +19,13 @@ type Client interface {
 	SendFile(filename string, contentType string, data []byte, caption string) error
 }
 
+// RetryClient is implemented by clients that opt out of the dispatcher's
+// retries, e.g. because a failed delivery may already have had side effects.
+type RetryClient interface {
+	Client
+	Retryable() bool
+}
+
 type Clients struct {
 	List []Client
 }
@@ -74,23 +82,42 @@ func NewDispatcher(o DispatcherOptions) *Dispatcher {
 }
 
 func (d *Dispatcher) worker(w clientWorker) {
+	attempts := 5
+	if rc, ok := w.c.(RetryClient); ok && !rc.Retryable() {
+		attempts = 1
+	}
 	for j := range w.ch {
-		for attempt := 0; attempt < 5; attempt++ {
+		for attempt := 0; attempt < attempts; attempt++ {
 			err := j.fn(w.c)
 			if err == nil {
 				break
 			}
 			d.ui.Warn("%s notify error: %v (attempt %d)", w.c.Name(), err, attempt+1)
+			if attempt+1 == attempts {
+				break
+			}
 			time.Sleep(time.Duration(500+attempt*500) * time.Millisecond)
 		}
 	}
 }
 
+// MaxAttachBytes returns the smallest attachment limit of the enabled
+// clients, or 0 when none of them has one.
+func (d *Dispatcher) MaxAttachBytes() int {
+	max := 0
+	for _, w := range d.workers {
+		if v := w.c.MaxAttachBytes(); v > 0 && (max == 0 || v < max) {
+			max = v
+		}
+	}
+	return max
+}
+
 func (d *Dispatcher) BroadcastText(text string) error {
 	if len(d.workers) == 0 {
 		return errors.New("no notification clients enabled")
 	}
-	return d.enqueue(func(c Client) error {
+	return d.enqueue(nil, func(c Client) error {
 		return c.SendText(text)
 	})
 }
@@ -103,18 +130,70 @@ func (d *Dispatcher) BroadcastFile(filename string, contentType string, data []b
 	cp := caption
 	buf := data
 	ct := contentType
-	return d.enqueue(func(c Client) error {
+	return d.enqueue(nil, func(c Client) error {
 		return c.SendFile(fn, ct, buf, cp)
 	})
 }
 
-func (d *Dispatcher) enqueue(fn func(Client) error) error {
+// BroadcastMessage sends m to every client (or m.Destinations): MessageClients
+// get the structured message, the others get its rendered text.
+func (d *Dispatcher) BroadcastMessage(m Message) error {
+	if len(d.workers) == 0 {
+		return errors.New("no notification clients enabled")
+	}
+	text := m.Text()
+	return d.enqueue(m.Destinations, func(c Client) error {
+		if mc, ok := c.(MessageClient); ok {
+			return mc.SendMessage(m)
+		}
+		return c.SendText(text)
+	})
+}
+
+// BroadcastMessageFile sends an attachment described by m. FileMessageClients
+// publish it as a structured message; the others get a regular file upload.
+func (d *Dispatcher) BroadcastMessageFile(m Message, filename string, contentType string, data []byte) error {
+	if len(d.workers) == 0 {
+		return errors.New("no notification clients enabled")
+	}
+	return d.enqueue(m.Destinations, func(c Client) error {
+		if fc, ok := c.(FileMessageClient); ok {
+			return fc.SendMessageFile(m, filename, contentType, data)
+		}
+		return c.SendFile(filename, contentType, data, m.Title)
+	})
+}
+
+// BroadcastIncident triggers (or resolves) an incident on every client that
+// implements IncidentClient. Other clients ignore it.
+func (d *Dispatcher) BroadcastIncident(inc Incident, resolve bool) error {
+	if len(d.workers) == 0 {
+		return errors.New("no notification clients enabled")
+	}
+	return d.enqueue(inc.Destinations, func(c Client) error {
+		ic, ok := c.(IncidentClient)
+		if !ok {
+			return nil
+		}
+		if resolve {
+			return ic.Resolve(inc)
+		}
+		return ic.Trigger(inc)
+	})
+}
+
+// enqueue queues fn for the clients named in to, or for all clients when to
+// is empty.
+func (d *Dispatcher) enqueue(to []string, fn func(Client) error) error {
 	d.mu.Lock()
 	if d.closed {
 		d.mu.Unlock()
 		return errors.New("dispatcher closed")
 	}
 	for _, w := range d.workers {
+		if len(to) > 0 && !slices.Contains(to, w.c.Name()) {
+			continue
+		}
 		w.ch <- job{fn: fn}
 	}
 	d.mu.Unlock()
@@ -151,6 +230,13 @@ func WantsLifecycle(notifyOn []string, item string) bool {
 	return false
 }
 
+// callbackNames are the callbacks BuildClients knows, for validating
+// alert rule destinations.
+var callbackNames = []string{
+	"discord", "slack", "telegram", "webhook", "pagerduty", "opsgenie",
+	"mqtt", "nats", "redis", "exec", "file", "syslog",
+}
+
 func BuildClients(httpc *http.Client, cfg *config.Config) (Clients, error) {
 	cbs := cfg.Notify.Callbacks
 	if len(cbs) == 0 {
@@ -174,7 +260,7 @@ func BuildClients(httpc *http.Client, cfg *config.Config) (Clients, error) {
 			if cfg.Discord.WebhookURL == "" {
 				return Clients{}, errors.New("discord enabled but webhook_url is empty")
 			}
-			c, err := NewDiscordClient(httpc, cfg.Discord.WebhookURL, cfg.Notify.Attach.PartMaxBytes["discord"])
+			c, err := NewDiscordClient(httpc, cfg.Discord.WebhookURL, cfg.Notify.Attach.PartMaxBytes["discord"], cfg.Discord.Interactive)
 			if err != nil {
 				return Clients{}, err
 			}
@@ -184,7 +270,7 @@ func BuildClients(httpc *http.Client, cfg *config.Config) (Clients, error) {
 			if cfg.Slack.WebhookURL == "" && cfg.Slack.BotToken == "" {
 				return Clients{}, errors.New("slack enabled but webhook_url/bot_token not set")
 			}
-			c, err := NewSlackClient(httpc, cfg.Slack.WebhookURL, cfg.Slack.BotToken, cfg.Slack.Channel)
+			c, err := NewSlackClient(httpc, cfg.Slack.WebhookURL, cfg.Slack.BotToken, cfg.Slack.Channel, cfg.Slack.Interactive)
 			if err != nil {
 				return Clients{}, err
 			}
@@ -193,7 +279,7 @@ func BuildClients(httpc *http.Client, cfg *config.Config) (Clients, error) {
 			if cfg.Telegram.BotToken == "" || cfg.Telegram.ChatID == "" {
 				return Clients{}, errors.New("telegram enabled but bot_token/chat_id not set")
 			}
-			c, err := NewTelegramClient(httpc, cfg.Telegram.BotToken, cfg.Telegram.ChatID, cfg.Telegram.ParseMode)
+			c, err := NewTelegramClient(httpc, cfg.Telegram.APIURL, cfg.Telegram.BotToken, cfg.Telegram.ChatID, cfg.Telegram.ParseMode)
 			if err != nil {
 				return Clients{}, err
 			}
@@ -202,7 +288,76 @@ func BuildClients(httpc *http.Client, cfg *config.Config) (Clients, error) {
 			if cfg.Webhook.URL == "" {
 				return Clients{}, errors.New("webhook enabled but url is empty")
 			}
-			c, err := NewWebhookClient(httpc, cfg.Webhook.URL, cfg.Webhook.Headers)
+			c, err := NewWebhookClient(httpc, cfg.Webhook)
+			if err != nil {
+				return Clients{}, err
+			}
+			out = append(out, c)
+		case "pagerduty":
+			if cfg.PagerDuty.RoutingKey == "" {
+				return Clients{}, errors.New("pagerduty enabled but routing_key is empty")
+			}
+			c, err := NewPagerDutyClient(httpc, cfg.PagerDuty)
+			if err != nil {
+				return Clients{}, err
+			}
+			out = append(out, c)
+		case "opsgenie":
+			if cfg.Opsgenie.APIKey == "" {
+				return Clients{}, errors.New("opsgenie enabled but api_key is empty")
+			}
+			c, err := NewOpsgenieClient(httpc, cfg.Opsgenie)
+			if err != nil {
+				return Clients{}, err
+			}
+			out = append(out, c)
+		case "mqtt":
+			if cfg.MQTT.URL == "" {
+				return Clients{}, errors.New("mqtt enabled but url is empty")
+			}
+			c, err := NewMQTTClient(cfg.MQTT, cfg.Transport)
+			if err != nil {
+				return Clients{}, err
+			}
+			out = append(out, c)
+		case "nats":
+			if cfg.NATS.URL == "" {
+				return Clients{}, errors.New("nats enabled but url is empty")
+			}
+			c, err := NewNATSClient(cfg.NATS, cfg.Transport)
+			if err != nil {
+				return Clients{}, err
+			}
+			out = append(out, c)
+		case "redis":
+			if cfg.Redis.URL == "" {
+				return Clients{}, errors.New("redis enabled but url is empty")
+			}
+			c, err := NewRedisClient(cfg.Redis, cfg.Transport)
+			if err != nil {
+				return Clients{}, err
+			}
+			out = append(out, c)
+		case "exec":
+			if len(cfg.Exec.Command) == 0 {
+				return Clients{}, errors.New("exec enabled but command is empty")
+			}
+			c, err := NewExecClient(cfg.Exec)
+			if err != nil {
+				return Clients{}, err
+			}
+			out = append(out, c)
+		case "file":
+			if cfg.File.Path == "" {
+				return Clients{}, errors.New("file enabled but path is empty")
+			}
+			c, err := NewFileClient(cfg.File)
+			if err != nil {
+				return Clients{}, err
+			}
+			out = append(out, c)
+		case "syslog":
+			c, err := NewSyslogClient(cfg.Syslog, cfg.Transport)
 			if err != nil {
 				return Clients{}, err
 			}
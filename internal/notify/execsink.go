@@ -0,0 +1,157 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haltman-io/gorunandcallme/internal/config"
+	"github.com/haltman-io/gorunandcallme/internal/util"
+)
+
+// ExecClient runs a local command for every notification, e.g. a script
+// that opens a ticket. The message goes to stdin, metadata to env vars.
+type ExecClient struct {
+	argv    []string
+	format  string
+	timeout time.Duration
+}
+
+func NewExecClient(cfg config.ExecConfig) (*ExecClient, error) {
+	if len(cfg.Command) == 0 || strings.TrimSpace(cfg.Command[0]) == "" {
+		return nil, errors.New("exec command is empty")
+	}
+
+	format := strings.ToLower(cfg.Format)
+	switch format {
+	case "", "text":
+		format = "text"
+	case "json":
+	default:
+		return nil, errors.New("exec format must be text|json")
+	}
+
+	timeout := 30 * time.Second
+	if cfg.Timeout != "" {
+		d, err := util.ParseExtendedDuration(cfg.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("exec timeout: %w", err)
+		}
+		timeout = d
+	}
+
+	return &ExecClient{
+		argv:    append([]string{}, cfg.Command...),
+		format:  format,
+		timeout: timeout,
+	}, nil
+}
+
+func (e *ExecClient) Name() string        { return "exec" }
+func (e *ExecClient) MaxTextChars() int   { return 0 }
+func (e *ExecClient) MaxAttachBytes() int { return 0 }
+
+// Retryable is false: a command that failed may already have acted (opened
+// a ticket, paged someone), so it is run once per notification.
+func (e *ExecClient) Retryable() bool { return false }
+
+func (e *ExecClient) SendText(text string) error {
+	return e.SendMessage(Message{Kind: KindBatch, Body: text})
+}
+
+func (e *ExecClient) SendFile(filename string, contentType string, data []byte, caption string) error {
+	return e.SendMessage(fileMessage(filename, data, caption))
+}
+
+func (e *ExecClient) SendMessageFile(m Message, filename string, contentType string, data []byte) error {
+	return e.SendMessage(attachmentMessage(m, filename, data))
+}
+
+func (e *ExecClient) SendMessage(m Message) error {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	var stdin []byte
+	if e.format == "json" {
+		stdin = append(marshalEvent(m), '\n')
+	} else {
+		stdin = []byte(plainText(m))
+	}
+
+	cmd := exec.CommandContext(ctx, e.argv[0], e.argv[1:]...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Env = append(sinkEnviron(), messageEnv(m)...)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		msg := strings.TrimSpace(string(out))
+		if len(msg) > 500 {
+			msg = msg[:500] + "..."
+		}
+		if msg != "" {
+			return fmt.Errorf("exec %s: %w: %s", e.argv[0], err, msg)
+		}
+		return fmt.Errorf("exec %s: %w", e.argv[0], err)
+	}
+	return nil
+}
+
+// sinkEnviron is os.Environ without the worker handshake variables
+// (GORUNANDCALLME_WORKER_*), which must never reach a nested run.
+func sinkEnviron() []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, "GORUNANDCALLME_WORKER_") {
+			continue
+		}
+		env = append(env, kv)
+	}
+	return env
+}
+
+// messageEnv exports message metadata as GORUNANDCALLME_* variables.
+// Extra fields become GORUNANDCALLME_FIELD_<NAME>.
+func messageEnv(m Message) []string {
+	env := []string{
+		"GORUNANDCALLME_KIND=" + m.Kind,
+		"GORUNANDCALLME_STATE=" + m.StateOrKind(),
+		"GORUNANDCALLME_JOB_ID=" + m.JobID,
+		"GORUNANDCALLME_COMMAND=" + m.Command,
+		"GORUNANDCALLME_TITLE=" + m.Title,
+		"GORUNANDCALLME_LINES=" + strconv.Itoa(len(m.Lines)),
+	}
+	if m.ExitCode != nil {
+		env = append(env, "GORUNANDCALLME_EXIT_CODE="+strconv.Itoa(*m.ExitCode))
+	}
+
+	keys := make([]string, 0, len(m.Fields))
+	for k := range m.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		name := strings.ToUpper(strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+				return r
+			}
+			return '_'
+		}, k))
+		env = append(env, "GORUNANDCALLME_FIELD_"+name+"="+m.Fields[k])
+	}
+	return env
+}
+
+// plainText renders a message without chat markup.
+func plainText(m Message) string {
+	if m.Title == "" {
+		return m.Body + "\n"
+	}
+	return m.Title + "\n" + m.Body + "\n"
+}
@@ -0,0 +1,86 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/haltman-io/gorunandcallme/internal/config"
+)
+
+// FileClient appends every notification to a local file.
+// The file is reopened for each message so external rotation is safe.
+type FileClient struct {
+	path   string
+	format string
+
+	mu sync.Mutex
+}
+
+func NewFileClient(cfg config.FileSinkConfig) (*FileClient, error) {
+	if strings.TrimSpace(cfg.Path) == "" {
+		return nil, errors.New("file path is empty")
+	}
+	format := strings.ToLower(cfg.Format)
+	switch format {
+	case "", "text":
+		format = "text"
+	case "jsonl":
+	default:
+		return nil, errors.New("file format must be text|jsonl")
+	}
+	return &FileClient{path: cfg.Path, format: format}, nil
+}
+
+func (f *FileClient) Name() string        { return "file" }
+func (f *FileClient) MaxTextChars() int   { return 0 }
+func (f *FileClient) MaxAttachBytes() int { return 0 }
+
+func (f *FileClient) SendText(text string) error {
+	return f.SendMessage(Message{Kind: KindBatch, Body: text})
+}
+
+func (f *FileClient) SendFile(filename string, contentType string, data []byte, caption string) error {
+	return f.SendMessage(fileMessage(filename, data, caption))
+}
+
+func (f *FileClient) SendMessageFile(m Message, filename string, contentType string, data []byte) error {
+	return f.SendMessage(attachmentMessage(m, filename, data))
+}
+
+func (f *FileClient) SendMessage(m Message) error {
+	var entry []byte
+	if f.format == "jsonl" {
+		entry = append(marshalEvent(m), '\n')
+	} else {
+		header := fmt.Sprintf("=== %s [%s]", time.Now().UTC().Format(time.RFC3339), m.StateOrKind())
+		if m.JobID != "" {
+			header += " job=" + m.JobID
+		}
+		entry = []byte(header + "\n" + plainText(m) + "\n")
+	}
+
+	path := renderTopic(f.path, m)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	fh, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := fh.Write(entry); err != nil {
+		_ = fh.Close()
+		return err
+	}
+	return fh.Close()
+}
@@ -53,39 +53,3 @@ func (f *Filters) Allow(line string) bool {
 	}
 	return true
 }
-
-type Alerts struct {
-	patterns []*regexp.Regexp
-	context  int
-}
-
-func NewAlerts(cfg config.AlertsConfig) (*Alerts, error) {
-	a := &Alerts{context: cfg.IncludeContextLines}
-	for _, p := range cfg.Patterns {
-		r, err := regexp.Compile(p)
-		if err != nil {
-			return nil, err
-		}
-		a.patterns = append(a.patterns, r)
-	}
-	return a, nil
-}
-
-func (a *Alerts) Match(line string) bool {
-	if a == nil || len(a.patterns) == 0 {
-		return false
-	}
-	for _, r := range a.patterns {
-		if r.MatchString(line) {
-			return true
-		}
-	}
-	return false
-}
-
-func (a *Alerts) ContextLines() int {
-	if a == nil {
-		return 0
-	}
-	return a.context
-}
@@ -0,0 +1,168 @@
+package notify
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/haltman-io/gorunandcallme/internal/util"
+)
+
+const (
+	minStallCheck = time.Second
+	maxStallCheck = 10 * time.Second
+	digestCheck   = 5 * time.Second
+)
+
+// startWatch parses heartbeat and stall_after and, when either is set, alerts
+// can be held back or absence rules have a window, starts the goroutine
+// sending heartbeats, stall alerts, due alert digests and absence alerts.
+func (a *Aggregator) startWatch() error {
+	var err error
+	if a.cfg.Heartbeat != "" {
+		if a.heartbeat, err = util.ParseExtendedDuration(a.cfg.Heartbeat); err != nil {
+			return fmt.Errorf("invalid heartbeat: %w", err)
+		}
+	}
+	if a.cfg.StallAfter != "" {
+		if a.stallAfter, err = util.ParseExtendedDuration(a.cfg.StallAfter); err != nil {
+			return fmt.Errorf("invalid stall_after: %w", err)
+		}
+	}
+	if a.cfg.QuietSuccess {
+		a.heartbeat = 0 // progress messages only; stall alerts still fire
+	}
+	if a.heartbeat <= 0 && a.stallAfter <= 0 && !a.throttle.holds() && a.alert.absenceWindow() <= 0 {
+		return nil
+	}
+	go a.watch()
+	return nil
+}
+
+func (a *Aggregator) watch() {
+	var beat, check, digest, absent <-chan time.Time
+	if a.heartbeat > 0 {
+		t := time.NewTicker(a.heartbeat)
+		defer t.Stop()
+		beat = t.C
+	}
+	if a.stallAfter > 0 {
+		t := time.NewTicker(min(max(a.stallAfter/10, minStallCheck), maxStallCheck))
+		defer t.Stop()
+		check = t.C
+	}
+	if a.throttle.holds() {
+		t := time.NewTicker(digestCheck)
+		defer t.Stop()
+		digest = t.C
+	}
+	if w := a.alert.absenceWindow(); w > 0 {
+		t := time.NewTicker(min(max(w/10, minStallCheck), maxStallCheck))
+		defer t.Stop()
+		absent = t.C
+	}
+	for {
+		select {
+		case <-a.stop:
+			return
+		case <-beat:
+			a.sendHeartbeat()
+		case <-check:
+			a.checkStall()
+		case <-digest:
+			a.flushDigests(false)
+		case <-absent:
+			a.checkAbsent(false)
+		}
+	}
+}
+
+// touch records output activity. The first line after a stall sends a
+// "resumed" notification, except with quiet_success.
+func (a *Aggregator) touch(line string) {
+	now := time.Now()
+	a.mu.Lock()
+	idle := now.Sub(a.lastOutput)
+	resumed := a.stalled
+	a.seen++
+	a.lastLine = line
+	a.lastOutput = now
+	a.stalled = false
+	a.mu.Unlock()
+
+	if resumed && !a.cfg.QuietSuccess {
+		title := a.jobTitle("output resumed")
+		m := a.newMessage(KindLifecycle, title, fmt.Sprintf("Output resumed after %s:\n%s", roundSecond(idle), line))
+		m.State = "resumed"
+		m.Fields = map[string]string{"stalled_for": roundSecond(idle)}
+		a.send(m)
+	}
+}
+
+func (a *Aggregator) sendHeartbeat() {
+	now := time.Now()
+	a.mu.Lock()
+	elapsed := now.Sub(a.started)
+	idle := now.Sub(a.lastOutput)
+	seen := a.seen
+	last := a.lastLine
+	a.mu.Unlock()
+
+	body := fmt.Sprintf("Running for %s\nLines: %d", roundSecond(elapsed), seen)
+	if seen > 0 {
+		body += fmt.Sprintf("\nLast output: %s ago\n%s", roundSecond(idle), last)
+	} else {
+		body += "\nNo output yet"
+	}
+	m := a.newMessage(KindLifecycle, a.jobTitle("still running"), body)
+	m.State = "heartbeat"
+	m.Fields = map[string]string{
+		"elapsed": roundSecond(elapsed),
+		"lines":   strconv.FormatInt(seen, 10),
+	}
+	a.send(m)
+}
+
+// checkStall sends one alert per silent period longer than stall_after and,
+// with kill_on_stall, asks the runner to stop the command.
+func (a *Aggregator) checkStall() {
+	a.mu.Lock()
+	idle := time.Since(a.lastOutput)
+	fire := !a.stalled && idle >= a.stallAfter
+	if fire {
+		a.stalled = true
+	}
+	last := a.lastLine
+	a.mu.Unlock()
+	if !fire {
+		return
+	}
+
+	kill := a.cfg.KillOnStall && a.onStall != nil
+	body := fmt.Sprintf("No output for %s", roundSecond(idle))
+	if last != "" {
+		body += "\n\nLast line:\n" + last
+	}
+	if kill {
+		body += "\n\nStopping the command (kill_on_stall)."
+	}
+	m := a.newMessage(KindAlert, fmt.Sprintf("STALL: no output for %s", roundSecond(idle)), body)
+	m.Severity = "warn"
+	m.Fields = map[string]string{"stalled_for": roundSecond(idle)}
+	a.send(m)
+
+	if kill {
+		a.onStall(idle)
+	}
+}
+
+func (a *Aggregator) jobTitle(what string) string {
+	if a.jobName != "" {
+		return fmt.Sprintf("Job %s %s", a.jobName, what)
+	}
+	return "Job " + what
+}
+
+func roundSecond(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
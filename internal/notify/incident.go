@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Incident is an alert that should open (or close) an incident on an
+// incident-management platform instead of posting a chat message.
+type Incident struct {
+	// DedupKey groups repeats of the same finding into a single incident.
+	DedupKey string
+
+	JobID   string
+	Pattern string
+	Matched string
+	Summary string
+	Details string
+
+	// Severity is the alert rule's severity (info|warn|critical) when the
+	// rule sets one; clients map it onto their own levels. Empty means the
+	// client's configured default.
+	Severity string
+
+	// Destinations limits the incident to these callbacks (default all).
+	Destinations []string
+}
+
+// IncidentClient is implemented by clients that manage incidents.
+// They ignore regular batches and are driven by the alert matcher instead.
+type IncidentClient interface {
+	Client
+	Trigger(inc Incident) error
+	Resolve(inc Incident) error
+}
+
+// IncidentDedupKey derives a stable key from the job ID and the alert pattern,
+// so repeated matches of the same pattern in the same job update one incident.
+func IncidentDedupKey(jobID string, pattern string) string {
+	sum := sha256.Sum256([]byte(jobID + "\x00" + pattern))
+	return "gorunandcallme-" + hex.EncodeToString(sum[:16])
+}
+
+func truncateRunes(s string, max int) string {
+	if max <= 0 {
+		return s
+	}
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	if max <= 3 {
+		return string(r[:max])
+	}
+	return string(r[:max-3]) + "..."
+}
@@ -0,0 +1,146 @@
+package notify
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haltman-io/gorunandcallme/internal/event"
+)
+
+// Message kinds.
+const (
+	KindBatch     = "batch"
+	KindAlert     = "alert"
+	KindLifecycle = "lifecycle"
+)
+
+// Message is the structured form of a notification.
+// Chat clients receive Text(); clients implementing MessageClient receive the Message itself.
+type Message struct {
+	Kind    string // batch | alert | lifecycle
+	State   string // lifecycle state (started, finished); empty for batches and alerts
+	JobID   string
+	Command string
+	Title   string
+	Body    string
+	Lines   []string
+
+	Severity string // info | warn | critical; empty = derived from kind and exit code
+	ExitCode *int
+	Fields   map[string]string
+
+	// JobControls is set for background jobs, which chat clients can offer
+	// Stop/Send log/Mute buttons for.
+	JobControls bool
+
+	// Destinations limits delivery to these callbacks (default all).
+	Destinations []string
+}
+
+// MessageClient is implemented by clients that want structured notifications
+// (sinks, syslog, structured webhooks) instead of rendered text.
+type MessageClient interface {
+	Client
+	SendMessage(m Message) error
+}
+
+// Text renders the message the way chat clients display it.
+func (m Message) Text() string {
+	return m.Title + "\n" + WrapCodeBlockMarkdown(m.Body)
+}
+
+// StateOrKind returns the lifecycle state, or the kind for batches and alerts.
+// Used to template topics/subjects/channels.
+func (m Message) StateOrKind() string {
+	if m.State != "" {
+		return m.State
+	}
+	return m.Kind
+}
+
+// severityOrDefault returns Severity, or a default derived from the kind:
+// alerts and failed runs are "warn", everything else "info".
+func (m Message) severityOrDefault() string {
+	if m.Severity != "" {
+		return m.Severity
+	}
+	if m.Kind == KindAlert || m.ExitCode != nil && *m.ExitCode != 0 {
+		return "warn"
+	}
+	return "info"
+}
+
+// Event converts the message to the event schema used by --event-output.
+func (m Message) Event() event.Event {
+	fields := map[string]string{}
+	for k, v := range m.Fields {
+		fields[k] = v
+	}
+	if m.Title != "" {
+		fields["title"] = m.Title
+	}
+	if m.State != "" {
+		fields["state"] = m.State
+	}
+	if m.Severity != "" {
+		fields["severity"] = m.Severity
+	}
+	if m.ExitCode != nil {
+		fields["exit_code"] = strconv.Itoa(*m.ExitCode)
+	}
+	if len(m.Lines) > 0 {
+		fields["lines"] = strconv.Itoa(len(m.Lines))
+	}
+	return event.Event{
+		Time:    time.Now().UTC().Format(time.RFC3339Nano),
+		Type:    m.Kind,
+		JobID:   m.JobID,
+		Command: m.Command,
+		Message: m.Body,
+		Fields:  fields,
+	}
+}
+
+// FileMessageClient is implemented by MessageClients that publish attachments
+// as structured messages instead of uploading files.
+type FileMessageClient interface {
+	MessageClient
+	SendMessageFile(m Message, filename string, contentType string, data []byte) error
+}
+
+// attachmentMessage returns m carrying an attachment as its body.
+func attachmentMessage(m Message, filename string, data []byte) Message {
+	fields := map[string]string{"filename": filename}
+	for k, v := range m.Fields {
+		fields[k] = v
+	}
+	m.Body = string(data)
+	m.Fields = fields
+	return m
+}
+
+// fileMessage wraps a plain SendFile call as a batch message.
+func fileMessage(filename string, data []byte, caption string) Message {
+	return attachmentMessage(Message{Kind: KindBatch, Title: caption}, filename, data)
+}
+
+// renderTopic expands {job_id}, {kind} and {state} placeholders.
+func renderTopic(tmpl string, m Message) string {
+	jobID := m.JobID
+	if jobID == "" {
+		jobID = "none"
+	}
+	r := strings.NewReplacer(
+		"{job_id}", jobID,
+		"{kind}", m.Kind,
+		"{state}", m.StateOrKind(),
+	)
+	return r.Replace(tmpl)
+}
+
+func marshalEvent(m Message) []byte {
+	b, _ := json.Marshal(m.Event())
+	return b
+}
@@ -0,0 +1,179 @@
+package notify
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/haltman-io/gorunandcallme/internal/config"
+	"github.com/haltman-io/gorunandcallme/internal/util"
+)
+
+const defaultMQTTTopic = "gorunandcallme/{job_id}/{state}"
+
+// MQTTClient publishes each notification as an event JSON document (MQTT 3.1.1).
+type MQTTClient struct {
+	ep       sinkEndpoint
+	topic    string
+	clientID string
+	qos      byte
+	retain   bool
+	insecure bool
+}
+
+func NewMQTTClient(cfg config.MQTTConfig, tcfg config.TransportConfig) (*MQTTClient, error) {
+	ep, err := parseSinkURL(cfg.URL, "1883", "8883", []string{"mqtt", "tcp"}, []string{"mqtts", "ssl", "tls"})
+	if err != nil {
+		return nil, fmt.Errorf("mqtt: %w", err)
+	}
+	if cfg.Username != "" {
+		ep.user = cfg.Username
+	}
+	if cfg.Password != "" {
+		ep.password = cfg.Password
+	}
+	if cfg.QoS < 0 || cfg.QoS > 1 {
+		return nil, errors.New("mqtt: qos must be 0 or 1")
+	}
+
+	topic := cfg.Topic
+	if topic == "" {
+		topic = defaultMQTTTopic
+	}
+	clientID := cfg.ClientID
+	if clientID == "" {
+		clientID = util.NewID("gorunandcallme")
+	}
+
+	return &MQTTClient{
+		ep:       ep,
+		topic:    topic,
+		clientID: clientID,
+		qos:      byte(cfg.QoS),
+		retain:   cfg.Retain,
+		insecure: tcfg.InsecureTLS,
+	}, nil
+}
+
+func (c *MQTTClient) Name() string        { return "mqtt" }
+func (c *MQTTClient) MaxTextChars() int   { return 0 }
+func (c *MQTTClient) MaxAttachBytes() int { return 0 }
+
+func (c *MQTTClient) SendText(text string) error {
+	return c.SendMessage(Message{Kind: KindBatch, Body: text})
+}
+
+func (c *MQTTClient) SendFile(filename string, contentType string, data []byte, caption string) error {
+	return c.SendMessage(fileMessage(filename, data, caption))
+}
+
+func (c *MQTTClient) SendMessageFile(m Message, filename string, contentType string, data []byte) error {
+	return c.SendMessage(attachmentMessage(m, filename, data))
+}
+
+func (c *MQTTClient) SendMessage(m Message) error {
+	conn, err := c.ep.dial(c.insecure)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	if _, err := conn.Write(c.connectPacket()); err != nil {
+		return err
+	}
+	// CONNACK: 0x20 0x02 <flags> <return code>
+	var ack [4]byte
+	if _, err := io.ReadFull(r, ack[:]); err != nil {
+		return fmt.Errorf("mqtt connack: %w", err)
+	}
+	if ack[0] != 0x20 {
+		return fmt.Errorf("mqtt: unexpected packet 0x%02x", ack[0])
+	}
+	if ack[3] != 0 {
+		return fmt.Errorf("mqtt: connection refused (code %d)", ack[3])
+	}
+
+	topic := renderTopic(c.topic, m)
+	if _, err := conn.Write(c.publishPacket(topic, marshalEvent(m))); err != nil {
+		return err
+	}
+	if c.qos == 1 {
+		// PUBACK: 0x40 0x02 <packet id>
+		var puback [4]byte
+		if _, err := io.ReadFull(r, puback[:]); err != nil {
+			return fmt.Errorf("mqtt puback: %w", err)
+		}
+		if puback[0] != 0x40 {
+			return fmt.Errorf("mqtt: unexpected packet 0x%02x", puback[0])
+		}
+	}
+
+	_, _ = conn.Write([]byte{0xE0, 0x00}) // DISCONNECT
+	return nil
+}
+
+func (c *MQTTClient) connectPacket() []byte {
+	var vh bytes.Buffer
+	writeMQTTString(&vh, "MQTT")
+	vh.WriteByte(0x04) // protocol level 3.1.1
+
+	flags := byte(0x02) // clean session
+	if c.ep.user != "" {
+		flags |= 0x80
+		if c.ep.password != "" {
+			flags |= 0x40
+		}
+	}
+	vh.WriteByte(flags)
+	vh.Write([]byte{0x00, 0x3C}) // keepalive 60s
+
+	writeMQTTString(&vh, c.clientID)
+	if c.ep.user != "" {
+		writeMQTTString(&vh, c.ep.user)
+		if c.ep.password != "" {
+			writeMQTTString(&vh, c.ep.password)
+		}
+	}
+	return mqttPacket(0x10, vh.Bytes())
+}
+
+func (c *MQTTClient) publishPacket(topic string, payload []byte) []byte {
+	header := byte(0x30) | c.qos<<1
+	if c.retain {
+		header |= 0x01
+	}
+	var body bytes.Buffer
+	writeMQTTString(&body, topic)
+	if c.qos == 1 {
+		body.Write([]byte{0x00, 0x01}) // packet id (one publish per connection)
+	}
+	body.Write(payload)
+	return mqttPacket(header, body.Bytes())
+}
+
+func mqttPacket(header byte, body []byte) []byte {
+	out := []byte{header}
+	// Remaining length: variable-length encoding, 7 bits per byte.
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		out = append(out, b)
+		if n == 0 {
+			break
+		}
+	}
+	return append(out, body...)
+}
+
+func writeMQTTString(buf *bytes.Buffer, s string) {
+	buf.WriteByte(byte(len(s) >> 8))
+	buf.WriteByte(byte(len(s)))
+	buf.WriteString(s)
+}
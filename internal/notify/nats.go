@@ -0,0 +1,170 @@
+package notify
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/haltman-io/gorunandcallme/internal/config"
+)
+
+const (
+	defaultNATSSubject = "gorunandcallme.{job_id}.{state}"
+	// natsMaxPayload is the server's default max_payload, used when INFO
+	// does not announce one.
+	natsMaxPayload = 1000000
+)
+
+// NATSClient publishes each notification as an event JSON document (NATS core protocol).
+type NATSClient struct {
+	ep       sinkEndpoint
+	subject  string
+	token    string
+	insecure bool
+}
+
+func NewNATSClient(cfg config.NATSConfig, tcfg config.TransportConfig) (*NATSClient, error) {
+	ep, err := parseSinkURL(cfg.URL, "4222", "4222", []string{"nats"}, []string{"tls"})
+	if err != nil {
+		return nil, fmt.Errorf("nats: %w", err)
+	}
+	if cfg.Username != "" {
+		ep.user = cfg.Username
+	}
+	if cfg.Password != "" {
+		ep.password = cfg.Password
+	}
+
+	token := cfg.Token
+	// nats://token@host is the conventional token form.
+	if token == "" && ep.user != "" && ep.password == "" {
+		token = ep.user
+		ep.user = ""
+	}
+
+	subject := cfg.Subject
+	if subject == "" {
+		subject = defaultNATSSubject
+	}
+
+	return &NATSClient{
+		ep:       ep,
+		subject:  subject,
+		token:    token,
+		insecure: tcfg.InsecureTLS,
+	}, nil
+}
+
+func (c *NATSClient) Name() string        { return "nats" }
+func (c *NATSClient) MaxTextChars() int   { return 0 }
+func (c *NATSClient) MaxAttachBytes() int { return natsMaxPayload / 2 } // room for JSON escaping and the envelope
+
+func (c *NATSClient) SendText(text string) error {
+	return c.SendMessage(Message{Kind: KindBatch, Body: text})
+}
+
+func (c *NATSClient) SendFile(filename string, contentType string, data []byte, caption string) error {
+	return c.SendMessage(fileMessage(filename, data, caption))
+}
+
+func (c *NATSClient) SendMessageFile(m Message, filename string, contentType string, data []byte) error {
+	return c.SendMessage(attachmentMessage(m, filename, data))
+}
+
+func (c *NATSClient) SendMessage(m Message) error {
+	// TLS upgrade happens after INFO, so dial in plain TCP first.
+	plain := c.ep
+	plain.useTLS = false
+	conn, err := plain.dial(false)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("nats info: %w", err)
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return fmt.Errorf("nats: unexpected greeting %q", strings.TrimSpace(line))
+	}
+	var info struct {
+		TLSRequired bool `json:"tls_required"`
+		MaxPayload  int  `json:"max_payload"`
+	}
+	_ = json.Unmarshal([]byte(strings.TrimPrefix(line, "INFO ")), &info)
+
+	if c.ep.useTLS || info.TLSRequired {
+		tconn := tls.Client(conn, &tls.Config{
+			ServerName:         c.ep.host,
+			InsecureSkipVerify: c.insecure, // intended by user (-k)
+		})
+		_ = tconn.SetDeadline(time.Now().Add(sinkIOTimeout))
+		if err := tconn.Handshake(); err != nil {
+			return fmt.Errorf("nats tls: %w", err)
+		}
+		conn = tconn
+		r = bufio.NewReader(conn)
+	}
+
+	opts := map[string]any{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     "gorunandcallme",
+		"lang":     "go",
+		"protocol": 1,
+	}
+	if c.token != "" {
+		opts["auth_token"] = c.token
+	}
+	if c.ep.user != "" {
+		opts["user"] = c.ep.user
+		opts["pass"] = c.ep.password
+	}
+	connectJSON, _ := json.Marshal(opts)
+
+	maxPayload := info.MaxPayload
+	if maxPayload <= 0 {
+		maxPayload = natsMaxPayload
+	}
+	payload := marshalEvent(m)
+	// Oversized publishes are rejected by the server: keep the newest part of
+	// the body instead.
+	for len(payload) > maxPayload && m.Body != "" {
+		// JSON escaping can grow the body, so cut it proportionally.
+		cut := len(m.Body)*(len(payload)-maxPayload)/len(payload) + 1
+		m.Body = m.Body[min(cut, len(m.Body)):]
+		payload = marshalEvent(m)
+	}
+	subject := renderTopic(c.subject, m)
+
+	var out strings.Builder
+	out.WriteString("CONNECT " + string(connectJSON) + "\r\n")
+	fmt.Fprintf(&out, "PUB %s %d\r\n", subject, len(payload))
+	out.Write(payload)
+	out.WriteString("\r\nPING\r\n")
+	if _, err := conn.Write([]byte(out.String())); err != nil {
+		return err
+	}
+
+	// The server answers PONG once everything before it was processed,
+	// or -ERR if auth/publish failed.
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("nats: %w", err)
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.New("nats: " + strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
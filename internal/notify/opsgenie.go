@@ -0,0 +1,126 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/haltman-io/gorunandcallme/internal/config"
+)
+
+const defaultOpsgenieAPIURL = "https://api.opsgenie.com"
+
+type OpsgenieClient struct {
+	http            *http.Client
+	apiURL          string
+	apiKey          string
+	priority        string
+	tags            []string
+	resolveOnFinish bool
+}
+
+func NewOpsgenieClient(httpc *http.Client, cfg config.OpsgenieConfig) (*OpsgenieClient, error) {
+	if cfg.APIKey == "" {
+		return nil, errors.New("opsgenie api_key is empty")
+	}
+	apiURL := strings.TrimRight(cfg.APIURL, "/")
+	if apiURL == "" {
+		apiURL = defaultOpsgenieAPIURL
+	}
+	if _, err := url.Parse(apiURL); err != nil {
+		return nil, err
+	}
+
+	priority := strings.ToUpper(cfg.Priority)
+	switch priority {
+	case "P1", "P2", "P3", "P4", "P5":
+	case "":
+		priority = "P3"
+	default:
+		return nil, errors.New("opsgenie priority must be P1..P5")
+	}
+
+	return &OpsgenieClient{
+		http:            httpc,
+		apiURL:          apiURL,
+		apiKey:          cfg.APIKey,
+		priority:        priority,
+		tags:            cfg.Tags,
+		resolveOnFinish: cfg.ResolveOnFinish,
+	}, nil
+}
+
+func (o *OpsgenieClient) Name() string        { return "opsgenie" }
+func (o *OpsgenieClient) MaxTextChars() int   { return 15000 } // description limit
+func (o *OpsgenieClient) MaxAttachBytes() int { return 0 }
+
+// SendText is a no-op: Opsgenie only receives alerts via Trigger/Resolve.
+func (o *OpsgenieClient) SendText(text string) error { return nil }
+
+// SendFile is a no-op: attachments are not forwarded to Opsgenie.
+func (o *OpsgenieClient) SendFile(filename string, contentType string, data []byte, caption string) error {
+	return nil
+}
+
+func (o *OpsgenieClient) Trigger(inc Incident) error {
+	body := map[string]any{
+		"message":     truncateRunes(inc.Summary, 130),
+		"alias":       inc.DedupKey,
+		"description": truncateRunes(inc.Details, o.MaxTextChars()),
+		"priority":    o.priorityOf(inc),
+		"source":      "gorunandcallme",
+		"details": map[string]string{
+			"job_id":  inc.JobID,
+			"pattern": inc.Pattern,
+			"matched": truncateRunes(inc.Matched, 8000),
+		},
+	}
+	if len(o.tags) > 0 {
+		body["tags"] = o.tags
+	}
+	return o.post(o.apiURL+"/v2/alerts", body)
+}
+
+// priorityOf maps the rule severity of inc onto an Opsgenie priority.
+func (o *OpsgenieClient) priorityOf(inc Incident) string {
+	switch inc.Severity {
+	case SeverityCritical:
+		return "P1"
+	case SeverityWarn:
+		return "P3"
+	case SeverityInfo:
+		return "P5"
+	}
+	return o.priority
+}
+
+func (o *OpsgenieClient) Resolve(inc Incident) error {
+	if !o.resolveOnFinish {
+		return nil
+	}
+	body := map[string]any{
+		"source": "gorunandcallme",
+		"note":   "Job finished",
+	}
+	u := o.apiURL + "/v2/alerts/" + url.PathEscape(inc.DedupKey) + "/close?identifierType=alias"
+	return o.post(u, body)
+}
+
+func (o *OpsgenieClient) post(u string, body map[string]any) error {
+	b, _ := json.Marshal(body)
+	req, _ := http.NewRequest("POST", u, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "GenieKey "+o.apiKey)
+	resp, err := o.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return errors.New(resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package notify
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Run outcomes, usable in notify_on next to "start" and "finish".
+const (
+	OutcomeSuccess     = "success"
+	OutcomeFailure     = "failure"
+	OutcomeTimeout     = "timeout"
+	OutcomeInterrupted = "interrupted"
+)
+
+const defaultFailureLines = 20
+
+// ValidateNotifyOn rejects unknown notify_on items.
+func ValidateNotifyOn(items []string) error {
+	for _, v := range items {
+		switch v {
+		case "start", "finish", OutcomeSuccess, OutcomeFailure, OutcomeTimeout, OutcomeInterrupted:
+		default:
+			return fmt.Errorf("invalid notify-on %q (want start|finish|success|failure|timeout|interrupted)", v)
+		}
+	}
+	return nil
+}
+
+// WantsStart reports whether the start of a run is notified. quiet_success
+// holds everything back until the outcome is known.
+func (a *Aggregator) WantsStart() bool {
+	return a != nil && !a.cfg.QuietSuccess && WantsLifecycle(a.cfg.NotifyOn, "start")
+}
+
+// WantsFinish reports whether a run that ended with outcome and exitCode is
+// notified: "finish" covers every outcome, outcomes can be listed one by
+// one, and notify_on_exit codes are always notified. With quiet_success a
+// successful run is only notified for a listed exit code.
+func (a *Aggregator) WantsFinish(outcome string, exitCode int) bool {
+	if a == nil {
+		return false
+	}
+	if slices.Contains(a.cfg.NotifyOnExit, exitCode) {
+		return true
+	}
+	if a.cfg.QuietSuccess && outcome == OutcomeSuccess {
+		return false
+	}
+	return WantsLifecycle(a.cfg.NotifyOn, "finish") || WantsLifecycle(a.cfg.NotifyOn, outcome)
+}
+
+// Quiet reports whether the run should end without output notifications.
+func (a *Aggregator) Quiet(outcome string, exitCode int) bool {
+	return a != nil && a.cfg.QuietSuccess && outcome == OutcomeSuccess && !slices.Contains(a.cfg.NotifyOnExit, exitCode)
+}
+
+// Discard drops the output collected since the last flush.
+func (a *Aggregator) Discard() {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	a.lines = nil
+	a.mu.Unlock()
+}
+
+// SendFinish notifies the end of a run. Unsuccessful runs get a title naming
+// the outcome and the last stderr lines.
+func (a *Aggregator) SendFinish(fullCmd string, details string, outcome string, exitCode int) {
+	if a == nil {
+		return
+	}
+	elapsed := roundSecond(time.Since(a.started))
+
+	var what string
+	switch outcome {
+	case OutcomeFailure:
+		what = fmt.Sprintf("failed (exit %d)", exitCode)
+	case OutcomeTimeout:
+		what = "timed out"
+	case OutcomeInterrupted:
+		what = "interrupted"
+	default:
+		what = "finished"
+	}
+	msg := fmt.Sprintf("Finished `%s`\n%s\nduration: %s", fullCmd, details, elapsed)
+
+	a.mu.Lock()
+	stderr := append([]string{}, a.stderr...)
+	a.mu.Unlock()
+	if outcome != OutcomeSuccess && len(stderr) > 0 {
+		msg += fmt.Sprintf("\n\nLast %d stderr lines:\n%s", len(stderr), strings.Join(stderr, "\n"))
+	}
+
+	code := exitCode
+	a.sendLifecycle("finished", a.jobTitle(what), msg, &code, map[string]string{
+		"outcome":  outcome,
+		"duration": elapsed,
+	})
+}
+
+// keepStderr remembers the last failure_lines stderr lines.
+func (a *Aggregator) keepStderr(line string) {
+	n := a.cfg.FailureLines
+	if n == 0 {
+		n = defaultFailureLines
+	}
+	if n < 0 {
+		return
+	}
+	a.mu.Lock()
+	a.stderr = append(a.stderr, line)
+	if len(a.stderr) > n {
+		a.stderr = a.stderr[len(a.stderr)-n:]
+	}
+	a.mu.Unlock()
+}
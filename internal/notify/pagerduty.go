@@ -0,0 +1,135 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/haltman-io/gorunandcallme/internal/config"
+)
+
+const defaultPagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+type PagerDutyClient struct {
+	http            *http.Client
+	eventsURL       string
+	routingKey      string
+	source          string
+	severity        string
+	resolveOnFinish bool
+}
+
+func NewPagerDutyClient(httpc *http.Client, cfg config.PagerDutyConfig) (*PagerDutyClient, error) {
+	if cfg.RoutingKey == "" {
+		return nil, errors.New("pagerduty routing_key is empty")
+	}
+	eventsURL := cfg.EventsURL
+	if eventsURL == "" {
+		eventsURL = defaultPagerDutyEventsURL
+	}
+	if _, err := url.Parse(eventsURL); err != nil {
+		return nil, err
+	}
+
+	severity := strings.ToLower(cfg.Severity)
+	switch severity {
+	case "critical", "error", "warning", "info":
+	case "":
+		severity = "critical"
+	default:
+		return nil, errors.New("pagerduty severity must be critical|error|warning|info")
+	}
+
+	source := cfg.Source
+	if source == "" {
+		source, _ = os.Hostname()
+	}
+	if source == "" {
+		source = "gorunandcallme"
+	}
+
+	return &PagerDutyClient{
+		http:            httpc,
+		eventsURL:       eventsURL,
+		routingKey:      cfg.RoutingKey,
+		source:          source,
+		severity:        severity,
+		resolveOnFinish: cfg.ResolveOnFinish,
+	}, nil
+}
+
+func (p *PagerDutyClient) Name() string        { return "pagerduty" }
+func (p *PagerDutyClient) MaxTextChars() int   { return 1024 } // payload.summary limit
+func (p *PagerDutyClient) MaxAttachBytes() int { return 0 }
+
+// SendText is a no-op: PagerDuty only receives alerts via Trigger/Resolve.
+func (p *PagerDutyClient) SendText(text string) error { return nil }
+
+// SendFile is a no-op: PagerDuty does not accept attachments.
+func (p *PagerDutyClient) SendFile(filename string, contentType string, data []byte, caption string) error {
+	return nil
+}
+
+func (p *PagerDutyClient) Trigger(inc Incident) error {
+	body := map[string]any{
+		"routing_key":  p.routingKey,
+		"event_action": "trigger",
+		"dedup_key":    inc.DedupKey,
+		"payload": map[string]any{
+			"summary":  truncateRunes(inc.Summary, p.MaxTextChars()),
+			"source":   p.source,
+			"severity": p.severityOf(inc),
+			"custom_details": map[string]any{
+				"job_id":  inc.JobID,
+				"pattern": inc.Pattern,
+				"matched": inc.Matched,
+				"context": inc.Details,
+			},
+		},
+	}
+	return p.post(body)
+}
+
+// severityOf maps the rule severity of inc onto a PagerDuty severity.
+func (p *PagerDutyClient) severityOf(inc Incident) string {
+	switch inc.Severity {
+	case SeverityCritical:
+		return "critical"
+	case SeverityWarn:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	}
+	return p.severity
+}
+
+func (p *PagerDutyClient) Resolve(inc Incident) error {
+	if !p.resolveOnFinish {
+		return nil
+	}
+	body := map[string]any{
+		"routing_key":  p.routingKey,
+		"event_action": "resolve",
+		"dedup_key":    inc.DedupKey,
+	}
+	return p.post(body)
+}
+
+func (p *PagerDutyClient) post(body map[string]any) error {
+	b, _ := json.Marshal(body)
+	req, _ := http.NewRequest("POST", p.eventsURL, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return errors.New(resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package notify
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/haltman-io/gorunandcallme/internal/config"
+)
+
+const defaultRedisChannel = "gorunandcallme:{job_id}:{state}"
+
+// RedisClient publishes each notification as an event JSON document (Redis PUBLISH).
+type RedisClient struct {
+	ep       sinkEndpoint
+	channel  string
+	db       int
+	insecure bool
+}
+
+func NewRedisClient(cfg config.RedisConfig, tcfg config.TransportConfig) (*RedisClient, error) {
+	ep, err := parseSinkURL(cfg.URL, "6379", "6379", []string{"redis"}, []string{"rediss"})
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	if cfg.Password != "" {
+		ep.password = cfg.Password
+	}
+
+	db := 0
+	if p := strings.Trim(ep.path, "/"); p != "" {
+		db, err = strconv.Atoi(p)
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid db %q", p)
+		}
+	}
+
+	channel := cfg.Channel
+	if channel == "" {
+		channel = defaultRedisChannel
+	}
+
+	return &RedisClient{
+		ep:       ep,
+		channel:  channel,
+		db:       db,
+		insecure: tcfg.InsecureTLS,
+	}, nil
+}
+
+func (c *RedisClient) Name() string        { return "redis" }
+func (c *RedisClient) MaxTextChars() int   { return 0 }
+func (c *RedisClient) MaxAttachBytes() int { return 0 }
+
+func (c *RedisClient) SendText(text string) error {
+	return c.SendMessage(Message{Kind: KindBatch, Body: text})
+}
+
+func (c *RedisClient) SendFile(filename string, contentType string, data []byte, caption string) error {
+	return c.SendMessage(fileMessage(filename, data, caption))
+}
+
+func (c *RedisClient) SendMessageFile(m Message, filename string, contentType string, data []byte) error {
+	return c.SendMessage(attachmentMessage(m, filename, data))
+}
+
+func (c *RedisClient) SendMessage(m Message) error {
+	conn, err := c.ep.dial(c.insecure)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	var cmds [][]string
+	if c.ep.password != "" {
+		if c.ep.user != "" {
+			cmds = append(cmds, []string{"AUTH", c.ep.user, c.ep.password})
+		} else {
+			cmds = append(cmds, []string{"AUTH", c.ep.password})
+		}
+	}
+	if c.db != 0 {
+		cmds = append(cmds, []string{"SELECT", strconv.Itoa(c.db)})
+	}
+	cmds = append(cmds, []string{"PUBLISH", renderTopic(c.channel, m), string(marshalEvent(m))})
+
+	for _, args := range cmds {
+		if _, err := conn.Write(respCommand(args)); err != nil {
+			return err
+		}
+		if err := readRESPReply(r); err != nil {
+			return fmt.Errorf("redis %s: %w", args[0], err)
+		}
+	}
+	return nil
+}
+
+func respCommand(args []string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	return []byte(b.String())
+}
+
+// readRESPReply reads a simple reply (+OK, :n) and turns -ERR into an error.
+func readRESPReply(r *bufio.Reader) error {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return errors.New("empty reply")
+	}
+	switch line[0] {
+	case '+', ':':
+		return nil
+	case '-':
+		return errors.New(line[1:])
+	default:
+		return fmt.Errorf("unexpected reply %q", line)
+	}
+}
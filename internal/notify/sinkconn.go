@@ -0,0 +1,96 @@
+package notify
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	sinkDialTimeout = 10 * time.Second
+	sinkIOTimeout   = 20 * time.Second
+)
+
+// sinkEndpoint is a parsed broker URL (mqtt://, nats://, redis:// and their TLS variants).
+type sinkEndpoint struct {
+	addr     string
+	useTLS   bool
+	host     string
+	user     string
+	password string
+	path     string
+}
+
+// parseSinkURL parses rawURL and returns the endpoint for the given schemes.
+// plain and secure list the URL schemes without and with TLS, each with its default port.
+func parseSinkURL(rawURL string, plainPort string, securePort string, plain []string, secure []string) (sinkEndpoint, error) {
+	if strings.TrimSpace(rawURL) == "" {
+		return sinkEndpoint{}, errors.New("url is empty")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return sinkEndpoint{}, err
+	}
+	ep := sinkEndpoint{path: u.Path}
+
+	scheme := strings.ToLower(u.Scheme)
+	switch {
+	case containsString(plain, scheme):
+	case containsString(secure, scheme):
+		ep.useTLS = true
+	default:
+		return sinkEndpoint{}, errors.New("unsupported url scheme: " + u.Scheme)
+	}
+
+	ep.host = u.Hostname()
+	if ep.host == "" {
+		return sinkEndpoint{}, errors.New("url has no host")
+	}
+	port := u.Port()
+	if port == "" {
+		port = plainPort
+		if ep.useTLS {
+			port = securePort
+		}
+	}
+	ep.addr = net.JoinHostPort(ep.host, port)
+
+	if u.User != nil {
+		ep.user = u.User.Username()
+		ep.password, _ = u.User.Password()
+	}
+	return ep, nil
+}
+
+// dial opens a connection with an overall I/O deadline. Each publish uses its
+// own short-lived connection; the dispatcher retries on error.
+func (ep sinkEndpoint) dial(insecure bool) (net.Conn, error) {
+	d := &net.Dialer{Timeout: sinkDialTimeout}
+	var conn net.Conn
+	var err error
+	if ep.useTLS {
+		conn, err = tls.DialWithDialer(d, "tcp", ep.addr, &tls.Config{
+			ServerName:         ep.host,
+			InsecureSkipVerify: insecure, // intended by user (-k)
+		})
+	} else {
+		conn, err = d.Dial("tcp", ep.addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Now().Add(sinkIOTimeout))
+	return conn, nil
+}
+
+func containsString(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -16,9 +16,11 @@ type SlackClient struct {
 	// Optional: for attachments (Slack incoming webhooks can't upload files).
 	botToken string
 	channel  string
+
+	interactive bool // job control buttons on lifecycle and alert messages
 }
 
-func NewSlackClient(httpc *http.Client, webhookURL, botToken, channel string) (*SlackClient, error) {
+func NewSlackClient(httpc *http.Client, webhookURL, botToken, channel string, interactive bool) (*SlackClient, error) {
 	if webhookURL == "" && botToken == "" {
 		return nil, errors.New("slack webhook url or bot token required")
 	}
@@ -27,7 +29,7 @@ func NewSlackClient(httpc *http.Client, webhookURL, botToken, channel string) (*
 			return nil, err
 		}
 	}
-	return &SlackClient{http: httpc, webhook: webhookURL, botToken: botToken, channel: channel}, nil
+	return &SlackClient{http: httpc, webhook: webhookURL, botToken: botToken, channel: channel, interactive: interactive}, nil
 }
 
 func (s *SlackClient) Name() string { return "slack" }
@@ -35,12 +37,55 @@ func (s *SlackClient) MaxTextChars() int { return 3500 }
 func (s *SlackClient) MaxAttachBytes() int { return 20000000 } // only used when file upload token is set
 
 func (s *SlackClient) SendText(text string) error {
-	if s.webhook == "" {
-		return errors.New("slack incoming webhook url not set")
-	}
-	body := map[string]any{
+	return s.post(map[string]any{
 		"text": text,
 		"mrkdwn": true,
+	})
+}
+
+// SendMessage adds Block Kit buttons for the job controls when interactive
+// controls are enabled; the action_id is the action, the value the job ID.
+func (s *SlackClient) SendMessage(m Message) error {
+	controls := jobControls(m)
+	text := m.Text()
+	if !s.interactive || len(controls) == 0 {
+		return s.SendText(text)
+	}
+	buttons := make([]map[string]any, 0, len(controls))
+	for _, c := range controls {
+		b := map[string]any{
+			"type":      "button",
+			"text":      map[string]any{"type": "plain_text", "text": c.Label},
+			"action_id": c.Action,
+			"value":     m.JobID,
+		}
+		if c.Danger {
+			b["style"] = "danger"
+			b["confirm"] = map[string]any{
+				"title":   map[string]any{"type": "plain_text", "text": "Stop job?"},
+				"text":    map[string]any{"type": "plain_text", "text": "Send SIGTERM to " + m.JobID + "."},
+				"confirm": map[string]any{"type": "plain_text", "text": "Stop"},
+				"deny":    map[string]any{"type": "plain_text", "text": "Cancel"},
+			}
+		}
+		buttons = append(buttons, b)
+	}
+	section := text
+	if r := []rune(section); len(r) > 2900 { // section text limit is 3000
+		section = string(r[:2900]) + "…"
+	}
+	return s.post(map[string]any{
+		"text": text,
+		"blocks": []map[string]any{
+			{"type": "section", "text": map[string]any{"type": "mrkdwn", "text": section}},
+			{"type": "actions", "block_id": "gorunandcallme", "elements": buttons},
+		},
+	})
+}
+
+func (s *SlackClient) post(body map[string]any) error {
+	if s.webhook == "" {
+		return errors.New("slack incoming webhook url not set")
 	}
 	b, _ := json.Marshal(body)
 	req, _ := http.NewRequest("POST", s.webhook, bytes.NewReader(b))
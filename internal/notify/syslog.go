@@ -0,0 +1,259 @@
+package notify
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haltman-io/gorunandcallme/internal/config"
+)
+
+// syslogSDID is the RFC 5424 structured-data ID used for job metadata.
+// 32473 is the IANA private enterprise number reserved for documentation.
+const syslogSDID = "gorunandcallme@32473"
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+var syslogSeverities = map[string]int{
+	"emerg": 0, "alert": 1, "crit": 2, "err": 3, "error": 3,
+	"warning": 4, "warn": 4, "notice": 5, "info": 6, "debug": 7,
+}
+
+// SyslogClient emits RFC 5424 messages over udp, tcp, tls or the local unix socket.
+type SyslogClient struct {
+	network    string
+	address    string
+	facility   int
+	appName    string
+	hostname   string
+	severities map[string]int
+	maxBytes   int
+	insecure   bool
+}
+
+func NewSyslogClient(cfg config.SyslogConfig, tcfg config.TransportConfig) (*SyslogClient, error) {
+	network := strings.ToLower(cfg.Network)
+	if network == "" {
+		network = "unix"
+	}
+	address := cfg.Address
+	switch network {
+	case "unix":
+		if address == "" {
+			address = "/dev/log"
+		}
+	case "udp", "tcp", "tls":
+		if address == "" {
+			return nil, errors.New("syslog address is required for " + network)
+		}
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			return nil, fmt.Errorf("syslog address: %w", err)
+		}
+	default:
+		return nil, errors.New("syslog network must be udp|tcp|tls|unix")
+	}
+
+	facilityName := strings.ToLower(cfg.Facility)
+	if facilityName == "" {
+		facilityName = "local0"
+	}
+	facility, ok := syslogFacilities[facilityName]
+	if !ok {
+		return nil, errors.New("unknown syslog facility: " + cfg.Facility)
+	}
+
+	severities := map[string]int{
+		KindBatch: 6, // info
+		KindAlert: 4, // warning
+	}
+	for k, v := range cfg.Severities {
+		sev, ok := syslogSeverities[strings.ToLower(v)]
+		if !ok {
+			return nil, errors.New("unknown syslog severity: " + v)
+		}
+		severities[strings.ToLower(k)] = sev
+	}
+
+	appName := cfg.AppName
+	if appName == "" {
+		appName = "gorunandcallme"
+	}
+	hostname := cfg.Hostname
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+	}
+
+	maxBytes := cfg.MaxBytes
+	if maxBytes == 0 && network == "udp" {
+		maxBytes = 8192
+	}
+
+	return &SyslogClient{
+		network:    network,
+		address:    address,
+		facility:   facility,
+		appName:    appName,
+		hostname:   hostname,
+		severities: severities,
+		maxBytes:   maxBytes,
+		insecure:   tcfg.InsecureTLS,
+	}, nil
+}
+
+func (s *SyslogClient) Name() string      { return "syslog" }
+func (s *SyslogClient) MaxTextChars() int { return s.maxBytes }
+
+// MaxAttachBytes is 0: records are truncated to max_bytes on send, so syslog
+// must not shrink the attachment parts of the other clients.
+func (s *SyslogClient) MaxAttachBytes() int { return 0 }
+
+func (s *SyslogClient) SendText(text string) error {
+	return s.SendMessage(Message{Kind: KindBatch, Body: text})
+}
+
+func (s *SyslogClient) SendFile(filename string, contentType string, data []byte, caption string) error {
+	return s.SendMessage(fileMessage(filename, data, caption))
+}
+
+func (s *SyslogClient) SendMessageFile(m Message, filename string, contentType string, data []byte) error {
+	return s.SendMessage(attachmentMessage(m, filename, data))
+}
+
+func (s *SyslogClient) SendMessage(m Message) error {
+	msg := s.format(m, time.Now())
+	if s.maxBytes > 0 && len(msg) > s.maxBytes {
+		msg = msg[:s.maxBytes]
+	}
+
+	conn, err := s.dial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(sinkIOTimeout))
+
+	switch s.network {
+	case "tcp", "tls":
+		// RFC 6587 octet counting keeps multi-line batches in one message.
+		_, err = conn.Write([]byte(strconv.Itoa(len(msg)) + " " + msg))
+	default:
+		_, err = conn.Write([]byte(msg))
+	}
+	return err
+}
+
+func (s *SyslogClient) dial() (net.Conn, error) {
+	d := &net.Dialer{Timeout: sinkDialTimeout}
+	switch s.network {
+	case "tls":
+		host, _, _ := net.SplitHostPort(s.address)
+		return tls.DialWithDialer(d, "tcp", s.address, &tls.Config{
+			ServerName:         host,
+			InsecureSkipVerify: s.insecure, // intended by user (-k)
+		})
+	case "unix":
+		conn, err := d.Dial("unixgram", s.address)
+		if err == nil {
+			return conn, nil
+		}
+		return d.Dial("unix", s.address)
+	default:
+		return d.Dial(s.network, s.address)
+	}
+}
+
+// format renders m as an RFC 5424 message.
+func (s *SyslogClient) format(m Message, now time.Time) string {
+	pri := s.facility*8 + s.severity(m)
+
+	sd := []string{
+		sdParam("kind", m.Kind),
+	}
+	if m.JobID != "" {
+		sd = append(sd, sdParam("job_id", m.JobID))
+	}
+	if m.State != "" {
+		sd = append(sd, sdParam("state", m.State))
+	}
+	if m.ExitCode != nil {
+		sd = append(sd, sdParam("exit_code", strconv.Itoa(*m.ExitCode)))
+	}
+	if m.Command != "" {
+		sd = append(sd, sdParam("command", m.Command))
+	}
+	if len(m.Lines) > 0 {
+		sd = append(sd, sdParam("lines", strconv.Itoa(len(m.Lines))))
+	}
+
+	return fmt.Sprintf("<%d>1 %s %s %s %d %s [%s %s] %s",
+		pri,
+		now.UTC().Format(time.RFC3339Nano),
+		syslogHeaderField(s.hostname, 255),
+		syslogHeaderField(s.appName, 48),
+		os.Getpid(),
+		syslogHeaderField(m.Kind, 32),
+		syslogSDID,
+		strings.Join(sd, " "),
+		strings.TrimRight(plainText(m), "\n"),
+	)
+}
+
+func (s *SyslogClient) severity(m Message) int {
+	// An explicit message severity (alert rule, stall) wins over the
+	// per-kind mapping.
+	switch m.Severity {
+	case SeverityInfo:
+		return 6 // info
+	case SeverityWarn:
+		return 4 // warning
+	case SeverityCritical:
+		return 2 // crit
+	}
+	if m.State != "" {
+		if v, ok := s.severities[m.State]; ok {
+			return v
+		}
+		if m.ExitCode != nil && *m.ExitCode != 0 {
+			return 3 // err
+		}
+		return 5 // notice
+	}
+	if v, ok := s.severities[m.Kind]; ok {
+		return v
+	}
+	return 6 // info
+}
+
+// sdParam renders one SD-PARAM, escaping '"', '\' and ']' as required by RFC 5424.
+func sdParam(name string, value string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+	return name + `="` + r.Replace(value) + `"`
+}
+
+// syslogHeaderField returns a printable-ASCII header field, or NILVALUE.
+func syslogHeaderField(s string, max int) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r > 32 && r < 127 {
+			b.WriteRune(r)
+		}
+	}
+	out := b.String()
+	if out == "" {
+		return "-"
+	}
+	if len(out) > max {
+		out = out[:max]
+	}
+	return out
+}
@@ -11,19 +11,33 @@ import (
 	"strings"
 )
 
+// DefaultTelegramAPIURL is the public Bot API.
+const DefaultTelegramAPIURL = "https://api.telegram.org"
+
+// TelegramMethodURL is the URL of a Bot API method; an empty apiURL means
+// DefaultTelegramAPIURL.
+func TelegramMethodURL(apiURL, botToken, method string) string {
+	if apiURL == "" {
+		apiURL = DefaultTelegramAPIURL
+	}
+	return fmt.Sprintf("%s/bot%s/%s", strings.TrimRight(apiURL, "/"), url.PathEscape(botToken), method)
+}
+
 type TelegramClient struct {
 	http      *http.Client
+	apiURL    string
 	botToken  string
 	chatID    string
 	parseMode string
 }
 
-func NewTelegramClient(httpc *http.Client, botToken, chatID, parseMode string) (*TelegramClient, error) {
+func NewTelegramClient(httpc *http.Client, apiURL, botToken, chatID, parseMode string) (*TelegramClient, error) {
 	if botToken == "" || chatID == "" {
 		return nil, errors.New("telegram bot_token and chat_id are required")
 	}
 	return &TelegramClient{
 		http:      httpc,
+		apiURL:    apiURL,
 		botToken:  botToken,
 		chatID:    chatID,
 		parseMode: parseMode,
@@ -35,7 +49,7 @@ func (t *TelegramClient) MaxTextChars() int { return 3800 } // keep margin below
 func (t *TelegramClient) MaxAttachBytes() int { return 45000000 }
 
 func (t *TelegramClient) SendText(text string) error {
-	api := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", url.PathEscape(t.botToken))
+	api := TelegramMethodURL(t.apiURL, t.botToken, "sendMessage")
 
 	// Telegram MarkdownV2 requires escaping.
 	escaped := EscapeMarkdownV2(text)
@@ -62,7 +76,7 @@ func (t *TelegramClient) SendText(text string) error {
 }
 
 func (t *TelegramClient) SendFile(filename string, contentType string, data []byte, caption string) error {
-	api := fmt.Sprintf("https://api.telegram.org/bot%s/sendDocument", url.PathEscape(t.botToken))
+	api := TelegramMethodURL(t.apiURL, t.botToken, "sendDocument")
 
 	var buf bytes.Buffer
 	w := multipart.NewWriter(&buf)
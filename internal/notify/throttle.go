@@ -0,0 +1,215 @@
+package notify
+
+import (
+	"container/list"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/haltman-io/gorunandcallme/internal/config"
+	"github.com/haltman-io/gorunandcallme/internal/util"
+)
+
+const (
+	digestSamples = 5    // matched lines quoted in a digest
+	maxDedupLines = 4096 // remembered lines; the oldest are evicted first
+)
+
+// alertThrottle decides which alert matches are sent right away. Matches
+// held back by the cooldown or the hourly cap are coalesced per rule and
+// sent later as a digest; duplicates within the dedup window are dropped.
+// All methods are called with Aggregator.mu held.
+type alertThrottle struct {
+	cooldown   time.Duration
+	dedup      time.Duration
+	maxPerHour int
+
+	lastSent map[*AlertRule]time.Time // last alert or digest
+	seen     map[string]*list.Element // matched line -> entry in order
+	order    *list.List               // seenLine entries, oldest first
+	sent     []time.Time              // alert messages within the last hour
+	pending  map[*AlertRule]*alertDigest
+}
+
+// seenLine is a matched line and when its dedup window started.
+type seenLine struct {
+	line  string
+	first time.Time
+}
+
+type alertDigest struct {
+	rule    *AlertRule
+	count   int
+	samples []string
+}
+
+// newAlertThrottle returns nil when no throttling is configured.
+func newAlertThrottle(cfg config.AlertsConfig) (*alertThrottle, error) {
+	t := &alertThrottle{
+		maxPerHour: cfg.MaxPerHour,
+		lastSent:   map[*AlertRule]time.Time{},
+		seen:       map[string]*list.Element{},
+		order:      list.New(),
+		pending:    map[*AlertRule]*alertDigest{},
+	}
+	var err error
+	if cfg.Cooldown != "" {
+		if t.cooldown, err = util.ParseExtendedDuration(cfg.Cooldown); err != nil {
+			return nil, fmt.Errorf("invalid alerts.cooldown: %w", err)
+		}
+	}
+	if cfg.DedupWindow != "" {
+		if t.dedup, err = util.ParseExtendedDuration(cfg.DedupWindow); err != nil {
+			return nil, fmt.Errorf("invalid alerts.dedup_window: %w", err)
+		}
+	}
+	if t.maxPerHour < 0 {
+		return nil, fmt.Errorf("invalid alerts.max_per_hour: %d", cfg.MaxPerHour)
+	}
+	if t.cooldown <= 0 && t.dedup <= 0 && t.maxPerHour == 0 {
+		return nil, nil
+	}
+	return t, nil
+}
+
+// holds reports whether matches can be held back for a digest.
+func (t *alertThrottle) holds() bool {
+	return t != nil && (t.cooldown > 0 || t.maxPerHour > 0)
+}
+
+// admit reports whether an alert for line should be sent now. Held back
+// matches are added to the rule's digest.
+func (t *alertThrottle) admit(r *AlertRule, line string, now time.Time) bool {
+	if t == nil {
+		return true
+	}
+	if t.dedup > 0 && t.duplicate(line, now) {
+		return false
+	}
+	if t.pending[r] != nil || t.cooling(r, now) || t.capped(now) {
+		t.hold(r, line)
+		return false
+	}
+	t.lastSent[r] = now
+	t.count(now)
+	return true
+}
+
+// duplicate reports whether line was seen within the dedup window, and
+// otherwise starts a window for it. At most maxDedupLines lines are
+// remembered; expired and then least recently started ones are evicted.
+func (t *alertThrottle) duplicate(line string, now time.Time) bool {
+	if e, ok := t.seen[line]; ok {
+		sl := e.Value.(*seenLine)
+		if now.Sub(sl.first) < t.dedup {
+			return true
+		}
+		sl.first = now
+		t.order.MoveToBack(e)
+	} else {
+		t.seen[line] = t.order.PushBack(&seenLine{line: line, first: now})
+	}
+	for e := t.order.Front(); e != nil; e = t.order.Front() {
+		sl := e.Value.(*seenLine)
+		if t.order.Len() <= maxDedupLines && now.Sub(sl.first) < t.dedup {
+			break
+		}
+		t.order.Remove(e)
+		delete(t.seen, sl.line)
+	}
+	return false
+}
+
+// cooling reports whether r was alerted on within the cooldown.
+func (t *alertThrottle) cooling(r *AlertRule, now time.Time) bool {
+	last, ok := t.lastSent[r]
+	return ok && t.cooldown > 0 && now.Sub(last) < t.cooldown
+}
+
+// capped reports whether max_per_hour messages were sent in the last hour.
+func (t *alertThrottle) capped(now time.Time) bool {
+	if t.maxPerHour <= 0 {
+		return false
+	}
+	i := 0
+	for i < len(t.sent) && now.Sub(t.sent[i]) >= time.Hour {
+		i++
+	}
+	t.sent = t.sent[i:]
+	return len(t.sent) >= t.maxPerHour
+}
+
+func (t *alertThrottle) count(now time.Time) {
+	if t.maxPerHour > 0 {
+		t.sent = append(t.sent, now)
+	}
+}
+
+func (t *alertThrottle) hold(r *AlertRule, line string) {
+	d := t.pending[r]
+	if d == nil {
+		d = &alertDigest{rule: r}
+		t.pending[r] = d
+	}
+	d.count++
+	if len(d.samples) < digestSamples {
+		d.samples = append(d.samples, line)
+	}
+}
+
+// due removes and returns the digests that can be sent now, one message
+// each. With all set, every pending digest is returned regardless of
+// cooldown and cap (used when the run ends).
+func (t *alertThrottle) due(now time.Time, all bool) []*alertDigest {
+	if t == nil || len(t.pending) == 0 {
+		return nil
+	}
+	rules := make([]*AlertRule, 0, len(t.pending))
+	for r := range t.pending {
+		rules = append(rules, r)
+	}
+	sortRules(rules)
+
+	var out []*alertDigest
+	for _, r := range rules {
+		if !all && (t.cooling(r, now) || t.capped(now)) {
+			continue
+		}
+		out = append(out, t.pending[r])
+		delete(t.pending, r)
+		t.lastSent[r] = now
+		t.count(now)
+	}
+	return out
+}
+
+// sendDigest sends a "N more matches" message for d.
+func (a *Aggregator) sendDigest(d *alertDigest) {
+	what := fmt.Sprintf("%d more matches", d.count)
+	if d.count == 1 {
+		what = "1 more match"
+	}
+	body := JoinLines(d.samples)
+	if extra := d.count - len(d.samples); extra > 0 {
+		body += fmt.Sprintf("\n… and %d more", extra)
+	}
+	m := a.newAlert(d.rule, alertTitle(d.rule, what), body)
+	m.Lines = d.samples
+	m.Fields["matches"] = strconv.Itoa(d.count)
+	m.Fields["digest"] = "true"
+	a.send(m)
+}
+
+// flushDigests sends the digests that are due (or all of them). Digests
+// that come due while alerts are muted are dropped.
+func (a *Aggregator) flushDigests(all bool) {
+	a.mu.Lock()
+	ds := a.throttle.due(time.Now(), all)
+	a.mu.Unlock()
+	if a.alertsMuted() {
+		return
+	}
+	for _, d := range ds {
+		a.sendDigest(d)
+	}
+}
@@ -2,31 +2,110 @@ package notify
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"mime/multipart"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/haltman-io/gorunandcallme/internal/config"
+)
+
+const (
+	defaultWebhookSignatureHeader = "X-Gorunandcallme-Signature"
+	defaultWebhookTimestampHeader = "X-Gorunandcallme-Timestamp"
 )
 
 type WebhookClient struct {
 	http    *http.Client
 	url     string
 	headers map[string]string
+
+	payload string // text | structured | template
+	tmpl    *template.Template
+
+	// Optional HMAC-SHA256 signing.
+	secret          []byte
+	signatureHeader string
+	timestampHeader string
 }
 
-func NewWebhookClient(httpc *http.Client, u string, headers map[string]string) (*WebhookClient, error) {
-	if u == "" {
+// WebhookPayload is the body sent in structured mode and the data passed to body templates.
+type WebhookPayload struct {
+	Time     string            `json:"time"`
+	JobID    string            `json:"job_id,omitempty"`
+	Kind     string            `json:"kind"`
+	State    string            `json:"state,omitempty"`
+	Severity string            `json:"severity"`
+	ExitCode *int              `json:"exit_code,omitempty"`
+	Command  string            `json:"command,omitempty"`
+	Title    string            `json:"title,omitempty"`
+	Text     string            `json:"text"`
+	Lines    []string          `json:"lines,omitempty"`
+	Fields   map[string]string `json:"fields,omitempty"`
+}
+
+func NewWebhookClient(httpc *http.Client, cfg config.WebhookConfig) (*WebhookClient, error) {
+	if cfg.URL == "" {
 		return nil, errors.New("webhook url is empty")
 	}
-	if _, err := url.Parse(u); err != nil {
+	if _, err := url.Parse(cfg.URL); err != nil {
 		return nil, err
 	}
-	return &WebhookClient{http: httpc, url: u, headers: headers}, nil
+
+	w := &WebhookClient{
+		http:            httpc,
+		url:             cfg.URL,
+		headers:         cfg.Headers,
+		payload:         strings.ToLower(cfg.Payload),
+		signatureHeader: cfg.SignatureHeader,
+		timestampHeader: cfg.TimestampHeader,
+	}
+
+	switch w.payload {
+	case "", "text":
+		w.payload = "text"
+	case "structured":
+	case "template":
+		if strings.TrimSpace(cfg.BodyTemplate) == "" {
+			return nil, errors.New("webhook payload=template requires body_template")
+		}
+		tmpl, err := template.New("webhook").Funcs(template.FuncMap{
+			"json": func(v any) (string, error) {
+				b, err := json.Marshal(v)
+				return string(b), err
+			},
+		}).Parse(cfg.BodyTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("webhook body_template: %w", err)
+		}
+		w.tmpl = tmpl
+	default:
+		return nil, errors.New("webhook payload must be text|structured|template")
+	}
+
+	if cfg.Secret != "" {
+		w.secret = []byte(cfg.Secret)
+		if w.signatureHeader == "" {
+			w.signatureHeader = defaultWebhookSignatureHeader
+		}
+		if w.timestampHeader == "" {
+			w.timestampHeader = defaultWebhookTimestampHeader
+		}
+	}
+	return w, nil
 }
 
-func (w *WebhookClient) Name() string { return "webhook" }
-func (w *WebhookClient) MaxTextChars() int { return 6000 }
+func (w *WebhookClient) Name() string        { return "webhook" }
+func (w *WebhookClient) MaxTextChars() int   { return 6000 }
 func (w *WebhookClient) MaxAttachBytes() int { return 10000000 }
 
 func (w *WebhookClient) SendText(text string) error {
@@ -34,20 +113,29 @@ func (w *WebhookClient) SendText(text string) error {
 		"text": text,
 	}
 	b, _ := json.Marshal(body)
-	req, _ := http.NewRequest("POST", w.url, bytes.NewReader(b))
-	req.Header.Set("Content-Type", "application/json")
-	for k, v := range w.headers {
-		req.Header.Set(k, v)
-	}
-	resp, err := w.http.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 300 {
-		return errors.New(resp.Status)
+	return w.post(b, "application/json")
+}
+
+func (w *WebhookClient) SendMessage(m Message) error {
+	switch w.payload {
+	case "structured":
+		b, err := json.Marshal(newWebhookPayload(m))
+		if err != nil {
+			return err
+		}
+		return w.post(b, "application/json")
+	case "template":
+		var buf bytes.Buffer
+		if err := w.tmpl.Execute(&buf, newWebhookPayload(m)); err != nil {
+			return fmt.Errorf("webhook body_template: %w", err)
+		}
+		if !json.Valid(buf.Bytes()) {
+			return errors.New("webhook body_template did not render valid JSON")
+		}
+		return w.post(buf.Bytes(), "application/json")
+	default:
+		return w.SendText(m.Text())
 	}
-	return nil
 }
 
 func (w *WebhookClient) SendFile(filename string, contentType string, data []byte, caption string) error {
@@ -62,11 +150,20 @@ func (w *WebhookClient) SendFile(filename string, contentType string, data []byt
 	_, _ = fw.Write(data)
 	_ = mp.Close()
 
-	req, _ := http.NewRequest("POST", w.url, &buf)
-	req.Header.Set("Content-Type", mp.FormDataContentType())
+	return w.post(buf.Bytes(), mp.FormDataContentType())
+}
+
+func (w *WebhookClient) post(body []byte, contentType string) error {
+	req, _ := http.NewRequest("POST", w.url, bytes.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
 	for k, v := range w.headers {
 		req.Header.Set(k, v)
 	}
+	if w.secret != nil {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(w.timestampHeader, ts)
+		req.Header.Set(w.signatureHeader, "sha256="+SignWebhook(w.secret, ts, body))
+	}
 	resp, err := w.http.Do(req)
 	if err != nil {
 		return err
@@ -77,3 +174,30 @@ func (w *WebhookClient) SendFile(filename string, contentType string, data []byt
 	}
 	return nil
 }
+
+// SignWebhook returns hex(HMAC-SHA256(secret, timestamp + "." + body)).
+// Receivers recompute it from the timestamp header and the raw body, and
+// should reject stale timestamps to prevent replays.
+func SignWebhook(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newWebhookPayload(m Message) WebhookPayload {
+	return WebhookPayload{
+		Time:     time.Now().UTC().Format(time.RFC3339),
+		JobID:    m.JobID,
+		Kind:     m.Kind,
+		State:    m.State,
+		Severity: m.severityOrDefault(),
+		ExitCode: m.ExitCode,
+		Command:  m.Command,
+		Title:    m.Title,
+		Text:     m.Body,
+		Lines:    m.Lines,
+		Fields:   m.Fields,
+	}
+}
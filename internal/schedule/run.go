@@ -0,0 +1,262 @@
+package schedule
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/haltman-io/gorunandcallme/internal/job"
+)
+
+const (
+	// lateAfter is how far behind an activation may be and still count as on
+	// time rather than missed.
+	lateAfter = time.Minute
+
+	// maxCatchUp bounds the runs started by the catchup policy.
+	maxCatchUp = 100
+)
+
+// Add validates s, assigns its ID and first activation, and stores it.
+func Add(st *Store, s Schedule) (Schedule, error) {
+	if s.Name != "" {
+		if _, err := st.Resolve(s.Name); err == nil {
+			return Schedule{}, fmt.Errorf("schedule: name %q is already used", s.Name)
+		}
+	}
+	if len(s.RunnerArgs) == 0 {
+		return Schedule{}, errors.New("schedule: no command given")
+	}
+	if s.Missed == "" {
+		s.Missed = MissedSkip
+	}
+
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return Schedule{}, fmt.Errorf("schedule: id: %w", err)
+	}
+	s.ID = "sched-" + hex.EncodeToString(b)
+	s.CreatedAt = time.Now()
+
+	spec, err := s.Spec()
+	if err != nil {
+		return Schedule{}, err
+	}
+	s.NextRun = spec.Next(s.CreatedAt)
+	if s.NextRun.IsZero() {
+		return Schedule{}, fmt.Errorf("schedule: %q never fires", s.SpecString())
+	}
+	if err := st.Write(s); err != nil {
+		return Schedule{}, err
+	}
+	return s, nil
+}
+
+// Tick starts the runs of every schedule that is due at now and reports
+// whether any schedule is active (not paused), i.e. whether the supervisor
+// must keep running.
+func Tick(js *job.Store, st *Store, now time.Time, log io.Writer) bool {
+	list, err := st.List()
+	if err != nil {
+		fmt.Fprintf(log, "schedule: %v\n", err)
+		return false
+	}
+	active := false
+	for _, s := range list {
+		if s.Paused {
+			continue
+		}
+		active = true
+		if now.Before(s.NextRun) {
+			continue
+		}
+		_, err := st.Update(s.ID, func(s *Schedule) error {
+			return fire(js, s, now, log)
+		})
+		if err != nil {
+			fmt.Fprintf(log, "schedule %s: %v\n", s.ID, err)
+		}
+	}
+	return active
+}
+
+// fire starts the due runs of s according to its missed-run and overlap
+// policies and advances NextRun. It runs under the schedule lock.
+func fire(js *job.Store, s *Schedule, now time.Time, log io.Writer) error {
+	if s.Paused || now.Before(s.NextRun) {
+		return nil
+	}
+	spec, err := s.Spec()
+	if err != nil {
+		return err
+	}
+
+	due := dueTimes(spec, s.NextRun, now)
+	if len(due) == 0 {
+		s.NextRun = spec.Next(now)
+		return nil
+	}
+	latest := due[len(due)-1]
+
+	var runs []time.Time
+	switch s.Missed {
+	case MissedCatchUp:
+		runs = due
+	case MissedOnce:
+		runs = due[len(due)-1:]
+	default:
+		if now.Sub(latest) <= lateAfter {
+			runs = due[len(due)-1:]
+		}
+	}
+	next := spec.Next(now)
+
+	if !s.Overlap && s.LastJobID != "" {
+		if prev, err := js.Refresh(s.LastJobID); err == nil && !prev.Ended() {
+			if s.Missed == MissedCatchUp {
+				// Keep the backlog; start it once the previous run ends.
+				return nil
+			}
+			fmt.Fprintf(log, "schedule %s: skipped run at %s: previous run %s is still %s\n",
+				s.ID, latest.Format(time.RFC3339), prev.ID, prev.Status)
+			s.NextRun = next
+			return nil
+		}
+	}
+	if !s.Overlap && len(runs) > 1 {
+		// One at a time; the rest stay due.
+		runs = runs[:1]
+		next = spec.Next(runs[0])
+	}
+
+	if skipped := len(due) - len(runs); skipped > 0 && s.Missed != MissedCatchUp {
+		fmt.Fprintf(log, "schedule %s: skipped %d missed run(s) (policy=%s)\n", s.ID, skipped, s.Missed)
+	}
+
+	for _, at := range runs {
+		res, err := launch(js, s)
+		if err != nil {
+			s.LastError = err.Error()
+			fmt.Fprintf(log, "schedule %s: start: %v\n", s.ID, err)
+			break
+		}
+		at := at
+		s.LastRun = &at
+		s.LastJobID = res.JobID
+		s.LastError = ""
+		s.Runs++
+		fmt.Fprintf(log, "schedule %s: started job %s (due %s)\n", s.ID, res.JobID, at.Format(time.RFC3339))
+	}
+	s.NextRun = next
+	return nil
+}
+
+// dueTimes lists the activations from first (inclusive) up to now, keeping
+// at most the newest maxCatchUp.
+func dueTimes(spec Spec, first, now time.Time) []time.Time {
+	var out []time.Time
+	for t := first; !t.IsZero() && !t.After(now); t = spec.Next(t) {
+		out = append(out, t)
+		if len(out) > maxCatchUp {
+			out = out[1:]
+		}
+	}
+	return out
+}
+
+func launch(js *job.Store, s *Schedule) (job.SpawnResult, error) {
+	labels := map[string]string{}
+	for k, v := range s.Labels {
+		labels[k] = v
+	}
+	labels["schedule"] = s.ID
+	if s.Name != "" {
+		labels["schedule"] = s.Name
+	}
+	return job.SpawnBackground(context.Background(), job.SpawnOptions{
+		RunnerPath: s.RunnerPath,
+		RunnerArgs: s.RunnerArgs,
+		Workdir:    s.Workdir,
+		Store:      js,
+		Command:    s.Command,
+		Tags:       s.Tags,
+		Name:       s.Name,
+		Labels:     labels,
+		Queue:      s.Queue,
+	})
+}
+
+// SetPaused pauses or resumes a schedule. Resuming starts counting from now:
+// activations that passed while paused are not treated as missed.
+func SetPaused(st *Store, ref string, paused bool) (Schedule, error) {
+	id, err := st.Resolve(ref)
+	if err != nil {
+		return Schedule{}, err
+	}
+	return st.Update(id, func(s *Schedule) error {
+		if s.Paused == paused {
+			return nil
+		}
+		s.Paused = paused
+		if !paused {
+			spec, err := s.Spec()
+			if err != nil {
+				return err
+			}
+			s.NextRun = spec.Next(time.Now())
+		}
+		return nil
+	})
+}
+
+// Remove deletes a schedule. Jobs it already started are kept.
+func Remove(st *Store, ref string) (string, error) {
+	id, err := st.Resolve(ref)
+	if err != nil {
+		return "", err
+	}
+	return id, st.Delete(id)
+}
+
+// CmdList prints schedules as a table or JSON.
+func CmdList(w io.Writer, st *Store, asJSON bool) error {
+	list, err := st.List()
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		if list == nil {
+			list = []Schedule{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(list)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tSCHEDULE\tMISSED\tSTATE\tNEXT\tLAST JOB\tCOMMAND")
+	for _, s := range list {
+		name, state, next, last := s.Name, "active", s.NextRun.Local().Format("2006-01-02 15:04"), s.LastJobID
+		if name == "" {
+			name = "-"
+		}
+		if s.Paused {
+			state, next = "paused", "-"
+		}
+		if s.Overlap {
+			state += ",overlap"
+		}
+		if last == "" {
+			last = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			s.ID, name, s.SpecString(), s.Missed, state, next, last, s.Command)
+	}
+	return tw.Flush()
+}
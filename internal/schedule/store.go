@@ -0,0 +1,223 @@
+package schedule
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/haltman-io/gorunandcallme/internal/job"
+)
+
+const DefaultSchedulesDir = "schedules"
+
+// MissedPolicy decides what happens to runs that fell due while no
+// supervisor was running.
+type MissedPolicy string
+
+const (
+	MissedSkip    MissedPolicy = "skip"    // drop them; wait for the next activation
+	MissedOnce    MissedPolicy = "once"    // run once, however many were missed
+	MissedCatchUp MissedPolicy = "catchup" // run every missed activation
+)
+
+// ParseMissedPolicy accepts skip, once or catchup (default: skip).
+func ParseMissedPolicy(s string) (MissedPolicy, error) {
+	switch p := MissedPolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case "":
+		return MissedSkip, nil
+	case MissedSkip, MissedOnce, MissedCatchUp:
+		return p, nil
+	case "catch-up":
+		return MissedCatchUp, nil
+	default:
+		return "", fmt.Errorf("unknown missed-run policy %q (want skip, once or catchup)", s)
+	}
+}
+
+// Schedule is a recurring background job definition, stored as
+// <state>/schedules/<id>.json.
+type Schedule struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+
+	// Exactly one of Cron and Every is set.
+	Cron  string `json:"cron,omitempty"`
+	Every string `json:"every,omitempty"`
+
+	Missed  MissedPolicy `json:"missed"`
+	Overlap bool         `json:"overlap,omitempty"` // start even if the previous run is still active
+	Paused  bool         `json:"paused,omitempty"`
+
+	// What to launch: the runner and its args, as for --background.
+	Command    string            `json:"command"`
+	RunnerPath string            `json:"runner_path"`
+	RunnerArgs []string          `json:"runner_args"`
+	Workdir    string            `json:"workdir,omitempty"`
+	Queue      string            `json:"queue,omitempty"`
+	Tags       []string          `json:"tags,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+
+	CreatedAt time.Time  `json:"created_at"`
+	NextRun   time.Time  `json:"next_run"`
+	LastRun   *time.Time `json:"last_run,omitempty"`
+	LastJobID string     `json:"last_job_id,omitempty"`
+	Runs      int        `json:"runs,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// Spec returns the parsed cron expression or interval.
+func (s Schedule) Spec() (Spec, error) {
+	switch {
+	case s.Cron != "" && s.Every != "":
+		return nil, errors.New("schedule: set either cron or every, not both")
+	case s.Cron != "":
+		return ParseCron(s.Cron)
+	case s.Every != "":
+		return ParseEvery(s.Every, s.CreatedAt)
+	default:
+		return nil, errors.New("schedule: cron or every is required")
+	}
+}
+
+// SpecString is the cron expression or "every <interval>", for display.
+func (s Schedule) SpecString() string {
+	if s.Cron != "" {
+		return s.Cron
+	}
+	return "every " + s.Every
+}
+
+type Store struct {
+	Dir string
+}
+
+// NewStore opens the schedules directory under the state dir.
+func NewStore(stateDir string) (*Store, error) {
+	dir := filepath.Join(stateDir, DefaultSchedulesDir)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create schedules dir: %w", err)
+	}
+	return &Store{Dir: dir}, nil
+}
+
+func (st *Store) path(id string) string {
+	return filepath.Join(st.Dir, id+".json")
+}
+
+func (st *Store) Write(s Schedule) error {
+	if s.ID == "" {
+		return errors.New("schedule: empty ID")
+	}
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal schedule: %w", err)
+	}
+	path := st.path(s.ID)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return fmt.Errorf("write tmp schedule: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("rename schedule: %w", err)
+	}
+	return nil
+}
+
+func (st *Store) Read(id string) (Schedule, error) {
+	b, err := os.ReadFile(st.path(id))
+	if err != nil {
+		return Schedule{}, fmt.Errorf("read schedule: %w", err)
+	}
+	var s Schedule
+	if err := json.Unmarshal(b, &s); err != nil {
+		return Schedule{}, fmt.Errorf("unmarshal schedule: %w", err)
+	}
+	return s, nil
+}
+
+// Update applies fn under a per-schedule lock, so the supervisor and the
+// schedule subcommands never overwrite each other's changes.
+func (st *Store) Update(id string, fn func(s *Schedule) error) (Schedule, error) {
+	unlock, err := st.lock(id)
+	if err != nil {
+		return Schedule{}, err
+	}
+	defer unlock()
+
+	s, err := st.Read(id)
+	if err != nil {
+		return Schedule{}, err
+	}
+	if err := fn(&s); err != nil {
+		return s, err
+	}
+	if err := st.Write(s); err != nil {
+		return s, err
+	}
+	return s, nil
+}
+
+// lock takes the per-schedule lock file.
+func (st *Store) lock(id string) (func(), error) {
+	unlock, err := job.LockFile(st.path(id) + ".lock")
+	if err != nil {
+		return nil, fmt.Errorf("lock schedule: %w", err)
+	}
+	return unlock, nil
+}
+
+// List returns all schedules, oldest first.
+func (st *Store) List() ([]Schedule, error) {
+	entries, err := os.ReadDir(st.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("readdir schedules: %w", err)
+	}
+	var out []Schedule
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		s, err := st.Read(strings.TrimSuffix(name, ".json"))
+		if err != nil {
+			continue
+		}
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (st *Store) Delete(id string) error {
+	if err := os.Remove(st.path(id)); err != nil {
+		return fmt.Errorf("remove schedule: %w", err)
+	}
+	return nil
+}
+
+// Resolve accepts a schedule ID or name.
+func (st *Store) Resolve(ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return "", errors.New("empty schedule id")
+	}
+	if _, err := os.Stat(st.path(ref)); err == nil && !strings.ContainsAny(ref, `/\`) {
+		return ref, nil
+	}
+	list, err := st.List()
+	if err != nil {
+		return "", err
+	}
+	for _, s := range list {
+		if s.Name == ref {
+			return s.ID, nil
+		}
+	}
+	return "", fmt.Errorf("no schedule with id or name %q", ref)
+}
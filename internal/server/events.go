@@ -0,0 +1,213 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haltman-io/gorunandcallme/internal/event"
+	"github.com/haltman-io/gorunandcallme/internal/job"
+)
+
+const (
+	defaultEventTail  = 20
+	eventPingInterval = 15 * time.Second
+)
+
+// handleEvents streams a job's output as Server-Sent Events. Each log line is
+// a "line" event carrying an event.Event (the --event-output schema); the
+// stream ends with a "job" event once the job has ended. Event IDs are log
+// offsets, so a reconnecting EventSource resumes where it left off.
+//
+// Query: tail=N lines of backlog (default 20; ignored when Last-Event-ID is sent).
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	m, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming not supported"))
+		return
+	}
+
+	tail := defaultEventTail
+	if v := r.URL.Query().Get("tail"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid tail %q", v))
+			return
+		}
+		tail = n
+	}
+
+	f, err := os.Open(m.LogPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if f != nil {
+		defer func() { _ = f.Close() }()
+	}
+
+	var offset int64
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		offset, _ = strconv.ParseInt(v, 10, 64)
+	} else if f != nil {
+		offset = tailOffset(f, tail)
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var pending []byte // partial last line
+	buf := make([]byte, 32*1024)
+	lastPing := time.Now()
+
+	for {
+		if f == nil {
+			// Queued jobs have no log yet.
+			if f, err = os.Open(m.LogPath); err == nil {
+				defer func() { _ = f.Close() }()
+			}
+		}
+		if f != nil {
+			if fi, err := f.Stat(); err == nil && fi.Size() < offset {
+				offset, pending = 0, nil // rotated (copy+truncate)
+			}
+			for {
+				n, err := f.ReadAt(buf, offset)
+				if n > 0 {
+					offset += int64(n)
+					pending = append(pending, buf[:n]...)
+					for {
+						i := bytes.IndexByte(pending, '\n')
+						if i < 0 {
+							break
+						}
+						line := strings.TrimRight(string(pending[:i]), "\r")
+						pending = pending[i+1:]
+						s.sendLine(w, m, line, offset-int64(len(pending)))
+					}
+				}
+				if err != nil || n == 0 {
+					break
+				}
+			}
+			flusher.Flush()
+		}
+
+		cur, err := s.opt.Store.Refresh(m.ID)
+		if err == nil && cur.Ended() {
+			if len(pending) > 0 {
+				s.sendLine(w, cur, string(pending), offset)
+			}
+			sendEvent(w, "job", "", jobEvent(cur))
+			flusher.Flush()
+			return
+		}
+
+		if time.Since(lastPing) > eventPingInterval {
+			_, _ = io.WriteString(w, ": ping\n\n")
+			flusher.Flush()
+			lastPing = time.Now()
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(s.opt.Poll):
+		}
+	}
+}
+
+// sendLine emits a log line; lines matching an alert rule carry its label in
+// Fields["alert"] and its severity in Fields["severity"]. Stream is left
+// empty: the job log interleaves stdout and stderr without marking them.
+func (s *Server) sendLine(w io.Writer, m job.Meta, line string, offset int64) {
+	ev := event.Event{
+		Time:    time.Now().UTC().Format(time.RFC3339Nano),
+		Type:    "line",
+		JobID:   m.ID,
+		Command: m.Command,
+		Message: s.opt.Redact.Apply(line),
+	}
+	ev.Fields = s.opt.Alerts.LineFields(ev.Message)
+	sendEvent(w, "line", strconv.FormatInt(offset, 10), ev)
+}
+
+// jobEvent describes the final job state, like the "job" events of --event-output.
+func jobEvent(m job.Meta) event.Event {
+	fields := map[string]string{"status": string(m.Status)}
+	if m.ExitCode != nil {
+		fields["exit_code"] = strconv.Itoa(*m.ExitCode)
+	}
+	if m.Signal != "" {
+		fields["signal"] = m.Signal
+	}
+	return event.Event{
+		Time:    time.Now().UTC().Format(time.RFC3339Nano),
+		Type:    "job",
+		JobID:   m.ID,
+		Command: m.Command,
+		Message: string(m.Status),
+		Fields:  fields,
+	}
+}
+
+func sendEvent(w io.Writer, name, id string, ev event.Event) {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	if id != "" {
+		fmt.Fprintf(w, "id: %s\n", id)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, b)
+}
+
+// tailOffset returns the offset of the last n lines of f.
+func tailOffset(f *os.File, n int) int64 {
+	fi, err := f.Stat()
+	if err != nil {
+		return 0
+	}
+	size := fi.Size()
+	if n == 0 {
+		return size
+	}
+	const chunk = 32 * 1024
+	pos := size
+	lines := 0
+	buf := make([]byte, chunk)
+	for pos > 0 {
+		k := int64(chunk)
+		if pos < k {
+			k = pos
+		}
+		pos -= k
+		if _, err := f.ReadAt(buf[:k], pos); err != nil && !errors.Is(err, io.EOF) {
+			return 0
+		}
+		for i := k - 1; i >= 0; i-- {
+			if buf[i] != '\n' || pos+i == size-1 {
+				continue
+			}
+			if lines++; lines == n {
+				return pos + i + 1
+			}
+		}
+	}
+	return 0
+}
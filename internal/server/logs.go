@@ -0,0 +1,185 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/haltman-io/gorunandcallme/internal/job"
+	"github.com/haltman-io/gorunandcallme/internal/util"
+)
+
+const (
+	defaultLogTail = 500
+	maxLogTail     = 10000
+)
+
+// LogLine is one line of GET /api/jobs/{ref}/log.
+type LogLine struct {
+	N        int    `json:"n"` // 1-based line number in the full log
+	Text     string `json:"text"`
+	Alert    string `json:"alert,omitempty"`    // matching alert rule (name or pattern)
+	Severity string `json:"severity,omitempty"` // severity of the alert rule
+}
+
+// LogResponse is the body of GET /api/jobs/{ref}/log.
+type LogResponse struct {
+	JobID     string    `json:"job_id"`
+	Lines     []LogLine `json:"lines"`
+	Matched   int       `json:"matched"`   // lines matching q/alerts, before tail
+	Truncated bool      `json:"truncated"` // Matched > len(Lines)
+}
+
+// handleLog returns the last lines of a job's full log (rotated segments
+// included), optionally filtered.
+//
+// Query: q=regex (case-insensitive), alerts=1 (alert lines only), tail=N
+// (default 500), download=1 (the raw log as an attachment).
+func (s *Server) handleLog(w http.ResponseWriter, r *http.Request) {
+	m, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+
+	rc, err := job.OpenFullLog(m.LogPath)
+	if errors.Is(err, os.ErrNotExist) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job %s has no log yet", m.ID))
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer func() { _ = rc.Close() }()
+
+	if q.Get("download") == "1" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Disposition", attachment(m.ID+".log"))
+		s.copyRedacted(w, rc)
+		return
+	}
+
+	tail := defaultLogTail
+	if v := q.Get("tail"); v != "" {
+		if tail, err = strconv.Atoi(v); err != nil || tail <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid tail %q", v))
+			return
+		}
+		tail = min(tail, maxLogTail)
+	}
+	var re *regexp.Regexp
+	if v := q.Get("q"); v != "" {
+		if re, err = regexp.Compile("(?i)" + v); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("q: %w", err))
+			return
+		}
+	}
+	alertsOnly := q.Get("alerts") == "1"
+
+	// Keep the last tail matches in a ring.
+	ring := make([]LogLine, 0, tail)
+	matched := 0
+	sc := bufio.NewScanner(rc)
+	sc.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		text := s.opt.Redact.Apply(strings.TrimRight(sc.Text(), "\r"))
+		plain := util.StripANSI(text)
+		if re != nil && !re.MatchString(plain) {
+			continue
+		}
+		l := LogLine{N: n, Text: text}
+		if rule := s.opt.Alerts.MatchRule(plain); rule != nil {
+			l.Alert, l.Severity = rule.Label(), rule.Severity
+		} else if alertsOnly {
+			continue
+		}
+		if len(ring) < tail {
+			ring = append(ring, l)
+		} else {
+			ring[matched%tail] = l
+		}
+		matched++
+	}
+	if err := sc.Err(); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if matched > tail {
+		k := matched % tail
+		ring = append(ring[k:], ring[:k]...)
+	}
+
+	writeJSON(w, http.StatusOK, LogResponse{
+		JobID:     m.ID,
+		Lines:     ring,
+		Matched:   matched,
+		Truncated: matched > len(ring),
+	})
+}
+
+// copyRedacted streams the log line by line with the redaction rules applied.
+func (s *Server) copyRedacted(w io.Writer, r io.Reader) {
+	if s.opt.Redact == nil {
+		_, _ = io.Copy(w, r)
+		return
+	}
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			body, nl := strings.CutSuffix(line, "\n")
+			_, _ = bw.WriteString(s.opt.Redact.Apply(body))
+			if nl {
+				_ = bw.WriteByte('\n')
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	_ = bw.Flush()
+}
+
+// handleFile downloads the n-th (0-based) output file of a job.
+func (s *Server) handleFile(w http.ResponseWriter, r *http.Request) {
+	m, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	n, err := strconv.Atoi(r.PathValue("n"))
+	if err != nil || n < 0 || n >= len(m.OutputFiles) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job %s has no output file %q", m.ID, r.PathValue("n")))
+		return
+	}
+	path := m.OutputFiles[n]
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("output file %s does not exist", path))
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer func() { _ = f.Close() }()
+	fi, err := f.Stat()
+	if err != nil || fi.IsDir() {
+		writeError(w, http.StatusNotFound, fmt.Errorf("output file %s is not a regular file", path))
+		return
+	}
+	w.Header().Set("Content-Disposition", attachment(filepath.Base(path)))
+	http.ServeContent(w, r, fi.Name(), fi.ModTime(), f)
+}
+
+func attachment(name string) string {
+	return fmt.Sprintf("attachment; filename=%q", name)
+}
@@ -0,0 +1,393 @@
+// Package server implements the opt-in HTTP API ("serve") over the job store:
+// REST endpoints for jobs, a Server-Sent Events stream of a job's output
+// using the same event schema as --event-output, and an embedded read-only
+// dashboard.
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haltman-io/gorunandcallme/internal/job"
+	"github.com/haltman-io/gorunandcallme/internal/notify"
+	"github.com/haltman-io/gorunandcallme/internal/util"
+)
+
+type Options struct {
+	Store *job.Store
+
+	// Token is required on every /api request, as "Authorization: Bearer
+	// <token>".
+	Token string
+
+	// ReadToken, if set, is also accepted on the GET routes, e.g. for the
+	// dashboard. It cannot start, stop or purge jobs.
+	ReadToken string
+
+	// RunnerPath is the runner executable (usually os.Executable()).
+	RunnerPath string
+
+	// RunnerBaseArgs are prepended to the runner args of jobs started through
+	// the API (config, profile, state dir).
+	RunnerBaseArgs []string
+
+	// Spawn starts a background job (through the daemon when one runs).
+	Spawn func(ctx context.Context, opt job.SpawnOptions) (job.SpawnResult, error)
+
+	// EventOutput is the configured event_output file, recorded as an output
+	// file of API jobs that do not pass --event-output.
+	EventOutput string
+
+	// Alerts marks log lines matching the alert patterns (may be nil).
+	Alerts *notify.Alerts
+
+	// Redact is applied to every log line served, downloads and SSE
+	// included (may be nil).
+	Redact *notify.Redactor
+
+	// Dashboard serves the read-only web UI at "/".
+	Dashboard bool
+
+	// Poll is the log polling interval of event streams; default 250ms.
+	Poll time.Duration
+}
+
+type Server struct {
+	opt Options
+	mux *http.ServeMux
+}
+
+func New(opt Options) (*Server, error) {
+	if opt.Store == nil {
+		return nil, errors.New("server: Store is nil")
+	}
+	if strings.TrimSpace(opt.Token) == "" {
+		return nil, errors.New("server: an API token is required")
+	}
+	if opt.ReadToken != "" && opt.ReadToken == opt.Token {
+		return nil, errors.New("server: the read-only token must differ from the API token")
+	}
+	if opt.Spawn == nil {
+		opt.Spawn = job.SpawnBackground
+	}
+	if opt.Poll <= 0 {
+		opt.Poll = 250 * time.Millisecond
+	}
+
+	s := &Server{opt: opt, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	s.mux.Handle("GET /api/jobs", s.read(s.handleList))
+	s.mux.Handle("POST /api/jobs", s.auth(s.handleStart))
+	s.mux.Handle("GET /api/jobs/{ref}", s.read(s.handleStatus))
+	s.mux.Handle("POST /api/jobs/{ref}/stop", s.auth(s.handleStop))
+	s.mux.Handle("DELETE /api/jobs/{ref}", s.auth(s.handlePurge))
+	s.mux.Handle("GET /api/jobs/{ref}/events", s.read(s.handleEvents))
+	s.mux.Handle("GET /api/jobs/{ref}/log", s.read(s.handleLog))
+	s.mux.Handle("GET /api/jobs/{ref}/files/{n}", s.read(s.handleFile))
+	if opt.Dashboard {
+		s.mux.Handle("GET /", dashboardHandler())
+	}
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// auth rejects requests without the API token.
+func (s *Server) auth(next http.HandlerFunc) http.Handler {
+	return s.requireToken(next, s.opt.Token)
+}
+
+// read rejects requests without the API token or the read-only token.
+func (s *Server) read(next http.HandlerFunc) http.Handler {
+	return s.requireToken(next, s.opt.Token, s.opt.ReadToken)
+}
+
+func (s *Server) requireToken(next http.HandlerFunc, tokens ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		got = strings.TrimSpace(got)
+		ok := false
+		for _, t := range tokens {
+			if got != "" && t != "" && subtle.ConstantTimeCompare([]byte(got), []byte(t)) == 1 {
+				ok = true
+			}
+		}
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="gorunandcallme"`)
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+			return
+		}
+		next(w, r)
+	})
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	opt := job.ListOptions{
+		Grep:   q.Get("grep"),
+		Labels: q["label"],
+	}
+	var err error
+	if opt.Statuses, err = job.ParseStatuses(q["status"]); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if v := q.Get("since"); v != "" {
+		if opt.Since, err = util.ParseExtendedDuration(v); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("since: %w", err))
+			return
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if opt.Limit, err = strconv.Atoi(v); err != nil || opt.Limit < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q", v))
+			return
+		}
+	}
+	jobs, err := job.ListJobs(s.opt.Store, opt)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if jobs == nil {
+		jobs = []job.Meta{}
+	}
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	m, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, m)
+}
+
+// StartRequest is the body of POST /api/jobs.
+type StartRequest struct {
+	Command []string          `json:"command"` // argv of the target tool
+	Args    []string          `json:"args"`    // extra runner flags, e.g. ["--notify-each", "10m"]
+	Name    string            `json:"name"`
+	Tags    []string          `json:"tags"`
+	Labels  map[string]string `json:"labels"`
+	Queue   string            `json:"queue"`
+	Workdir string            `json:"workdir"`
+}
+
+func (s *Server) handleStart(w http.ResponseWriter, r *http.Request) {
+	var req StartRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid body: %w", err))
+		return
+	}
+	if len(req.Command) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("command is required"))
+		return
+	}
+	if err := job.ValidateJobName(req.Name); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	for _, a := range req.Args {
+		flag, _, _ := strings.Cut(a, "=")
+		switch {
+		case flag == "--", flag == "--background", flag == "--queue":
+			writeError(w, http.StatusBadRequest, fmt.Errorf("args must not contain %s (use the request fields)", flag))
+			return
+		case flag == "--config", flag == "--state-dir", flag == "--profile",
+			strings.HasPrefix(flag, "--") && slices.Contains(s.opt.RunnerBaseArgs, flag):
+			// Fixed by the server (see RunnerBaseArgs).
+			writeError(w, http.StatusBadRequest, fmt.Errorf("args must not contain %s", flag))
+			return
+		}
+	}
+	workdir := req.Workdir
+	if workdir != "" {
+		if fi, err := os.Stat(workdir); err != nil || !fi.IsDir() {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("workdir %q is not a directory", workdir))
+			return
+		}
+	}
+
+	outputFiles, err := s.outputFiles(req.Args, workdir)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	runnerArgs := append([]string{}, s.opt.RunnerBaseArgs...)
+	runnerArgs = append(runnerArgs, req.Args...)
+	runnerArgs = append(append(runnerArgs, "--"), req.Command...)
+
+	// The runner must outlive the request.
+	res, err := s.opt.Spawn(context.WithoutCancel(r.Context()), job.SpawnOptions{
+		RunnerPath:  s.opt.RunnerPath,
+		RunnerArgs:  runnerArgs,
+		Workdir:     workdir,
+		Store:       s.opt.Store,
+		Command:     job.QuoteArgs(req.Command),
+		Tags:        util.NormalizeCSV(req.Tags),
+		Name:        req.Name,
+		Labels:      req.Labels,
+		OutputFiles: outputFiles,
+		Queue:       req.Queue,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Location", "/api/jobs/"+res.JobID)
+	writeJSON(w, http.StatusCreated, res.Meta)
+}
+
+// outputFiles returns the absolute paths of the files a job started with
+// args writes besides its log (--output, --event-output), like the CLI
+// records for "job export" and downloads.
+func (s *Server) outputFiles(args []string, workdir string) ([]string, error) {
+	var output string
+	eventOutput := s.opt.EventOutput
+	for i := 0; i < len(args); i++ {
+		flag, val, hasVal := strings.Cut(args[i], "=")
+		if !hasVal && i+1 < len(args) {
+			val = args[i+1]
+		}
+		switch flag {
+		case "-o", "--output":
+			output = val
+		case "--event-output":
+			eventOutput = val
+		default:
+			continue
+		}
+		if !hasVal {
+			i++
+		}
+	}
+	if workdir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		workdir = wd
+	}
+	var files []string
+	for _, f := range []string{output, eventOutput} {
+		if f == "" {
+			continue
+		}
+		if !filepath.IsAbs(f) {
+			f = filepath.Join(workdir, f)
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// StopRequest is the optional body of POST /api/jobs/{ref}/stop.
+type StopRequest struct {
+	Signal string `json:"signal"` // default TERM
+	Grace  string `json:"grace"`  // default 10s
+}
+
+func (s *Server) handleStop(w http.ResponseWriter, r *http.Request) {
+	m, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	var req StopRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid body: %w", err))
+			return
+		}
+	}
+	opt := job.StopOptions{Signal: req.Signal}
+	if req.Grace != "" {
+		d, err := time.ParseDuration(req.Grace)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("grace: %w", err))
+			return
+		}
+		opt.Grace = d
+	}
+	if !m.Active() {
+		writeError(w, http.StatusConflict, fmt.Errorf("job %s is not running (status=%s)", m.ID, m.Status))
+		return
+	}
+
+	var out strings.Builder
+	if err := job.CmdStop(&out, s.opt.Store, m.ID, opt); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	m, err := s.opt.Store.ReadMeta(m.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, m)
+}
+
+func (s *Server) handlePurge(w http.ResponseWriter, r *http.Request) {
+	m, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	if !m.Ended() {
+		writeError(w, http.StatusConflict, fmt.Errorf("job %s is %s; stop or cancel it first", m.ID, m.Status))
+		return
+	}
+	if err := s.opt.Store.DeleteJob(m.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// lookup resolves {ref} (ID, index or name) and writes 404 if there is no such job.
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) (job.Meta, bool) {
+	id, err := job.ResolveJobRef(s.opt.Store, r.PathValue("ref"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return job.Meta{}, false
+	}
+	m, err := s.opt.Store.Refresh(id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return job.Meta{}, false
+	}
+	if m.Status == job.StatusQueued {
+		if pos, err := job.QueuePositions(s.opt.Store); err == nil {
+			m.QueuePosition = pos[m.ID]
+		}
+	}
+	return m, true
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,328 @@
+// Read-only dashboard for "gorunandcallme serve". Everything comes from the
+// /api endpoints; the token is kept in sessionStorage.
+"use strict";
+
+const $ = (id) => document.getElementById(id);
+const TOKEN_KEY = "gorunandcallme.token";
+const LIST_REFRESH_MS = 5000;
+const LOG_TAIL = 500;
+const MAX_LOG_LINES = 5000;
+const ANSI = /\x1b\[[0-9;?]*[ -\/]*[@-~]/g;
+
+let listTimer = null;
+let stream = null;
+
+function token() {
+  return sessionStorage.getItem(TOKEN_KEY) || "";
+}
+
+// authFetch sends the token as a header; it is never put in URLs, where it
+// would end up in logs and the browser history.
+async function authFetch(path, opts = {}) {
+  const res = await fetch(path, { ...opts, headers: { Authorization: "Bearer " + token() } });
+  if (res.status === 401) {
+    sessionStorage.removeItem(TOKEN_KEY);
+    showLogin("Invalid token.");
+    throw new Error("unauthorized");
+  }
+  return res;
+}
+
+async function api(path) {
+  const res = await authFetch(path);
+  const body = await res.json();
+  if (!res.ok) {
+    throw new Error(body.error || res.statusText);
+  }
+  return body;
+}
+
+// download fetches path and saves the response under the server's filename.
+async function download(path, fallbackName) {
+  const res = await authFetch(path);
+  if (!res.ok) {
+    const body = await res.json().catch(() => ({}));
+    throw new Error(body.error || res.statusText);
+  }
+  const cd = res.headers.get("Content-Disposition") || "";
+  const m = cd.match(/filename="?([^";]+)"?/);
+  const url = URL.createObjectURL(await res.blob());
+  const a = el("a");
+  a.href = url;
+  a.download = m ? m[1] : fallbackName;
+  a.click();
+  setTimeout(() => URL.revokeObjectURL(url), 1000);
+}
+
+// follow reads the SSE stream at path with fetch (EventSource cannot send
+// headers) and calls on[event](data) per event. It returns a handle whose
+// close() stops the stream.
+function follow(path, on) {
+  const ctrl = new AbortController();
+  (async () => {
+    const res = await authFetch(path, { signal: ctrl.signal });
+    if (!res.ok) throw new Error(res.statusText);
+    const reader = res.body.pipeThrough(new TextDecoderStream()).getReader();
+    let buf = "";
+    for (;;) {
+      const { value, done } = await reader.read();
+      if (done) return;
+      buf += value;
+      let i;
+      while ((i = buf.indexOf("\n\n")) >= 0) {
+        const block = buf.slice(0, i);
+        buf = buf.slice(i + 2);
+        let event = "message";
+        const data = [];
+        for (const line of block.split("\n")) {
+          if (line.startsWith("event:")) event = line.slice(6).trim();
+          else if (line.startsWith("data:")) data.push(line.slice(5).replace(/^ /, ""));
+        }
+        if (data.length && on[event]) on[event](data.join("\n"));
+      }
+    }
+  })().catch((e) => {
+    if (e.name !== "AbortError") $("log-info").textContent = e.message;
+  });
+  return { close: () => ctrl.abort() };
+}
+
+function show(section) {
+  for (const id of ["login", "jobs", "job"]) {
+    $(id).hidden = id !== section;
+  }
+  $("logout").hidden = section === "login";
+}
+
+function showLogin(msg) {
+  stopTimers();
+  $("login-error").textContent = msg || "";
+  show("login");
+}
+
+function stopTimers() {
+  clearInterval(listTimer);
+  listTimer = null;
+  if (stream) {
+    stream.close();
+    stream = null;
+  }
+}
+
+function el(tag, text, cls) {
+  const e = document.createElement(tag);
+  if (text !== undefined) e.textContent = text;
+  if (cls) e.className = cls;
+  return e;
+}
+
+function duration(j) {
+  if (j.status === "queued" || j.status === "canceled") return "";
+  const start = new Date(j.started_at);
+  const end = j.ended_at ? new Date(j.ended_at) : new Date();
+  let s = Math.max(0, Math.round((end - start) / 1000));
+  const h = Math.floor(s / 3600);
+  const m = Math.floor((s % 3600) / 60);
+  s %= 60;
+  if (h) return `${h}h${m}m${s}s`;
+  if (m) return `${m}m${s}s`;
+  return `${s}s`;
+}
+
+function statusText(j) {
+  let t = j.status;
+  if (j.status === "queued" && j.queue_position) t += ` #${j.queue_position}`;
+  if (j.exit_code !== undefined && j.exit_code !== null) t += ` (${j.exit_code})`;
+  return t;
+}
+
+// ---- job list ----
+
+async function loadJobs() {
+  const params = new URLSearchParams();
+  if ($("status-filter").value) params.append("status", $("status-filter").value);
+  if ($("grep-filter").value) params.append("grep", $("grep-filter").value);
+  let jobs;
+  try {
+    jobs = await api("/api/jobs?" + params);
+  } catch (e) {
+    $("updated").textContent = e.message;
+    return;
+  }
+  const rows = $("job-rows");
+  rows.replaceChildren();
+  jobs.forEach((j, i) => {
+    const tr = el("tr");
+    tr.append(
+      el("td", String(i + 1)),
+      el("td", j.id),
+      el("td", j.name || ""),
+      el("td", statusText(j), "status-" + j.status),
+      el("td", duration(j)),
+      el("td", j.status === "queued" ? "" : new Date(j.started_at).toLocaleString()),
+      el("td", j.command || "", "command"),
+    );
+    tr.addEventListener("click", () => { location.hash = "job/" + encodeURIComponent(j.id); });
+    rows.append(tr);
+  });
+  $("jobs-empty").hidden = jobs.length > 0;
+  $("updated").textContent = "updated " + new Date().toLocaleTimeString();
+}
+
+function showJobs() {
+  stopTimers();
+  show("jobs");
+  loadJobs();
+  listTimer = setInterval(loadJobs, LIST_REFRESH_MS);
+}
+
+// ---- job view ----
+
+function renderMeta(j) {
+  $("job-title").textContent = (j.name ? j.name + " — " : "") + j.id;
+  const dl = $("job-meta");
+  dl.replaceChildren();
+  const add = (k, v) => {
+    if (v === undefined || v === null || v === "") return;
+    dl.append(el("dt", k), el("dd", String(v), k === "status" ? "status-" + j.status : ""));
+  };
+  add("status", statusText(j));
+  add("command", j.command);
+  add("duration", duration(j));
+  add("started", j.status === "queued" ? "" : new Date(j.started_at).toLocaleString());
+  add("ended", j.ended_at && new Date(j.ended_at).toLocaleString());
+  add("queue", j.queue);
+  add("tags", (j.tags || []).join(", "));
+  add("labels", Object.entries(j.labels || {}).map(([k, v]) => k + "=" + v).join(", "));
+  add("signal", j.signal);
+  add("error", j.error_text);
+  add("workdir", j.workdir);
+
+  const dls = $("downloads");
+  dls.replaceChildren();
+  const link = (text, href) => {
+    const a = el("a", text);
+    a.href = "#";
+    a.addEventListener("click", (e) => {
+      e.preventDefault();
+      download(href, text).catch((err) => { $("log-info").textContent = err.message; });
+    });
+    dls.append(a);
+  };
+  const base = "/api/jobs/" + encodeURIComponent(j.id);
+  link("full log", base + "/log?download=1");
+  (j.output_files || []).forEach((f, i) => link(f.split(/[\\/]/).pop(), base + "/files/" + i));
+}
+
+function appendLine(n, text, alert, severity) {
+  const log = $("log");
+  const atBottom = log.scrollTop + log.clientHeight >= log.scrollHeight - 4;
+  const line = el("span", undefined, "line" + (alert ? " alert sev-" + (severity || "warn") : ""));
+  if (alert) line.title = `alert (${severity || "warn"}): ${alert}`;
+  if (n) line.append(el("span", String(n), "n"));
+  line.append(document.createTextNode(text.replace(ANSI, "")));
+  log.append(line);
+  while (log.childElementCount > MAX_LOG_LINES) log.firstElementChild.remove();
+  if (atBottom) log.scrollTop = log.scrollHeight;
+}
+
+async function loadLog(id) {
+  if (stream) {
+    stream.close();
+    stream = null;
+  }
+  $("log").replaceChildren();
+  const base = "/api/jobs/" + encodeURIComponent(id);
+  const q = $("search").value;
+  const alertsOnly = $("alerts-only").checked;
+
+  if ($("live").checked && !q && !alertsOnly) {
+    $("log-info").textContent = "following…";
+    stream = follow(base + "/events?tail=" + LOG_TAIL, {
+      line: (data) => {
+        const ev = JSON.parse(data);
+        const f = ev.fields || {};
+        appendLine(0, ev.message || "", f.alert, f.severity);
+      },
+      job: () => {
+        stream.close();
+        stream = null;
+        $("log-info").textContent = "job ended";
+        api(base).then(renderMeta).catch(() => {});
+      },
+    });
+    return;
+  }
+
+  const params = new URLSearchParams({ tail: String(MAX_LOG_LINES) });
+  if (q) params.set("q", q);
+  if (alertsOnly) params.set("alerts", "1");
+  try {
+    const res = await api(base + "/log?" + params);
+    res.lines.forEach((l) => appendLine(l.n, l.text, l.alert, l.severity));
+    let info = `${res.matched} matching line${res.matched === 1 ? "" : "s"}`;
+    if (res.truncated) info += `, showing the last ${res.lines.length}`;
+    $("log-info").textContent = info;
+  } catch (e) {
+    $("log-info").textContent = e.message;
+  }
+}
+
+async function showJob(id) {
+  stopTimers();
+  show("job");
+  $("job-title").textContent = id;
+  $("job-meta").replaceChildren();
+  $("log").replaceChildren();
+  try {
+    renderMeta(await api("/api/jobs/" + encodeURIComponent(id)));
+  } catch (e) {
+    $("log-info").textContent = e.message;
+    return;
+  }
+  loadLog(id);
+}
+
+// ---- routing ----
+
+function route() {
+  if (!token()) {
+    showLogin();
+    return;
+  }
+  const m = location.hash.match(/^#job\/(.+)$/);
+  if (m) {
+    showJob(decodeURIComponent(m[1]));
+  } else {
+    showJobs();
+  }
+}
+
+function currentJob() {
+  const m = location.hash.match(/^#job\/(.+)$/);
+  return m ? decodeURIComponent(m[1]) : "";
+}
+
+let searchTimer = null;
+function reloadLogSoon() {
+  clearTimeout(searchTimer);
+  searchTimer = setTimeout(() => loadLog(currentJob()), 300);
+}
+
+$("login-form").addEventListener("submit", (e) => {
+  e.preventDefault();
+  sessionStorage.setItem(TOKEN_KEY, $("token").value.trim());
+  $("token").value = "";
+  route();
+});
+$("logout").addEventListener("click", () => {
+  sessionStorage.removeItem(TOKEN_KEY);
+  showLogin();
+});
+$("status-filter").addEventListener("change", loadJobs);
+$("grep-filter").addEventListener("input", loadJobs);
+$("search").addEventListener("input", reloadLogSoon);
+$("alerts-only").addEventListener("change", reloadLogSoon);
+$("live").addEventListener("change", reloadLogSoon);
+window.addEventListener("hashchange", route);
+route();
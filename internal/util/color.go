@@ -1,21 +1,44 @@
 package util
 
-import "fmt"
+import "os"
+
+// ANSI color codes used by ColorTag and Colorize.
+const (
+	ColorRed     = "31"
+	ColorGreen   = "32"
+	ColorYellow  = "33"
+	ColorMagenta = "35"
+	ColorCyan    = "36"
+	ColorGray    = "90"
+)
+
+var tagColors = map[string]string{
+	"INF": ColorCyan,
+	"WRN": ColorYellow,
+	"ERR": ColorRed,
+	"DBG": ColorMagenta,
+	"VRB": ColorGray,
+}
 
 func ColorTag(tag string) string {
 	// Minimal, modern: only tag gets color.
-	switch tag {
-	case "INF":
-		return fmt.Sprintf("[\x1b[36m%s\x1b[0m]", tag) // cyan
-	case "WRN":
-		return fmt.Sprintf("[\x1b[33m%s\x1b[0m]", tag) // yellow
-	case "ERR":
-		return fmt.Sprintf("[\x1b[31m%s\x1b[0m]", tag) // red
-	case "DBG":
-		return fmt.Sprintf("[\x1b[35m%s\x1b[0m]", tag) // magenta
-	case "VRB":
-		return fmt.Sprintf("[\x1b[90m%s\x1b[0m]", tag) // gray
-	default:
-		return fmt.Sprintf("[%s]", tag)
+	if c, ok := tagColors[tag]; ok {
+		return "[" + Colorize(c, tag) + "]"
+	}
+	return "[" + tag + "]"
+}
+
+// Colorize wraps s in an ANSI color sequence. The escape overhead is the same
+// for every color, so colored cells still line up in a tabwriter column.
+func Colorize(color string, s string) string {
+	return "\x1b[" + color + "m" + s + "\x1b[0m"
+}
+
+// IsTerminal reports whether f is a character device (a terminal).
+func IsTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
 	}
+	return fi.Mode()&os.ModeCharDevice != 0
 }